@@ -1,9 +1,29 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// JWTKeyConfig points at a PEM encoded key identified by its kid
+type JWTKeyConfig struct {
+	ID   string
+	Path string
+}
+
+// OIDCProviderConfig registers this app as a client of an OpenID Connect provider
+type OIDCProviderConfig struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
 type Config struct {
 	ServerPort    string
 	DBHost        string
@@ -13,20 +33,156 @@ type Config struct {
 	DBPort        string
 	DBSSLRootCert string
 	JWTSecret     string
+	// JWTActiveKey signs new tokens (RS256 or EdDSA, inferred from the key).
+	// When unset, tokens are signed with JWTSecret using HS256. Set by
+	// JWT_ACTIVE_KEY as "kid=path/to/private.pem".
+	JWTActiveKey JWTKeyConfig
+	// JWTRetiredKeys still verify tokens but are never used for signing. Set
+	// by JWT_RETIRED_KEYS as a comma separated list of "kid=path" pairs; keep
+	// a rotated out key here until the tokens it signed have expired.
+	JWTRetiredKeys  []JWTKeyConfig
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// RevocationCacheTTL bounds how long a "token not revoked" answer is cached
+	RevocationCacheTTL time.Duration
+	// AppBaseURL prefixes links sent to users by email
+	AppBaseURL string
+	// MailDir receives outgoing mail as .eml files; when empty mail is logged
+	MailDir          string
+	MailFrom         string
+	PasswordResetTTL time.Duration
+	// Email verification
+	EmailVerificationTTL         time.Duration
+	VerificationResendCooldown   time.Duration
+	VerificationResendDailyLimit int
+	// Two-factor authentication
+	TOTPIssuer  string
+	MFATokenTTL time.Duration
+	// Login brute-force protection
+	LoginFreeAttempts       int
+	LoginBackoffBase        time.Duration
+	LoginBackoffMax         time.Duration
+	AccountLockoutThreshold int
+	IPLockoutThreshold      int
+	LockoutDuration         time.Duration
+	AccountUnlockTTL        time.Duration
+	// TrustProxyHeaders takes client IPs from X-Real-IP/X-Forwarded-For
+	TrustProxyHeaders bool
+	// RequireVerifiedEmail restricts exchange features to verified accounts
+	RequireVerifiedEmail bool
+	// Password hashing; existing hashes are upgraded on the next login
+	PasswordHashAlgorithm string
+	BcryptCost            int
+	Argon2Memory          int // KiB
+	Argon2Iterations      int
+	Argon2Parallelism     int
+	// AccountDeletionGracePeriod is how long a deleted account can be restored
+	AccountDeletionGracePeriod time.Duration
+	// OIDCProviders enables sign-in with external identity providers
+	OIDCProviders []OIDCProviderConfig
+	// OIDCStateTTL bounds the time a user may spend at the provider
+	OIDCStateTTL time.Duration
+	// StorageBackend keeps uploaded files: "local" (served under /media) or "s3"
+	StorageBackend  string
+	StorageLocalDir string
+	S3Endpoint      string
+	S3Region        string
+	S3Bucket        string
+	S3AccessKeyID   string
+	S3SecretKey     string
+	// S3PublicURL is where clients download files, e.g. a CDN in front of the bucket
+	S3PublicURL string
+	// Avatar uploads
+	AvatarMaxBytes int64
+	AvatarSizes    []int
+	// LocationPrecisionMeters is how coarsely user positions are shown to others
+	LocationPrecisionMeters int
+	// MetadataProvider fills in book details from the ISBN: "openlibrary",
+	// "googlebooks", "fake" (fixtures from MetadataFixtures, or built-in ones)
+	// or "none"
+	MetadataProvider  string
+	GoogleBooksAPIKey string
+	MetadataFixtures  string
+	MetadataTimeout   time.Duration
+	MetadataCacheTTL  time.Duration
+	// The catalog is skipped for MetadataCircuitCooldown after
+	// MetadataCircuitThreshold consecutive failures
+	MetadataCircuitThreshold int
+	MetadataCircuitCooldown  time.Duration
 }
 
-// LoadConfig loads configuration from environment variables or defaults
-func LoadConfig() *Config {
-	return &Config{
-		ServerPort:    getEnv("SERVER_PORT", "3000"),
-		DBHost:        getEnv("DB_HOST", "localhost"),
-		DBUser:        getEnv("DB_USER", "postgres"),
-		DBPassword:    getEnv("DB_PASSWORD", ""),
-		DBName:        getEnv("DB_NAME", "book_exchange"),
-		DBPort:        getEnv("DB_PORT", "5432"),
-		DBSSLRootCert: getEnv("DB_SSL_ROOT_CERT", "ca.pem"), // Path to SSL certificate
-		JWTSecret:     getEnv("JWT_SECRET", ""),
+// LoadConfig loads configuration from environment variables or defaults. It
+// fails when a variable that is set cannot be used as is and falling back to
+// a default would weaken security, such as a malformed JWT key.
+func LoadConfig() (*Config, error) {
+	appBaseURL := strings.TrimRight(getEnv("APP_BASE_URL", "http://localhost:3000"), "/")
+	activeKey, err := parseKeyConfig(getEnv("JWT_ACTIVE_KEY", ""))
+	if err != nil {
+		return nil, fmt.Errorf("JWT_ACTIVE_KEY: %w", err)
 	}
+	retiredKeys, err := parseKeyConfigList(getEnv("JWT_RETIRED_KEYS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("JWT_RETIRED_KEYS: %w", err)
+	}
+	return &Config{
+		ServerPort:                   getEnv("SERVER_PORT", "3000"),
+		DBHost:                       getEnv("DB_HOST", "localhost"),
+		DBUser:                       getEnv("DB_USER", "postgres"),
+		DBPassword:                   getEnv("DB_PASSWORD", ""),
+		DBName:                       getEnv("DB_NAME", "book_exchange"),
+		DBPort:                       getEnv("DB_PORT", "5432"),
+		DBSSLRootCert:                getEnv("DB_SSL_ROOT_CERT", "ca.pem"), // Path to SSL certificate
+		JWTSecret:                    getEnv("JWT_SECRET", ""),
+		JWTActiveKey:                 activeKey,
+		JWTRetiredKeys:               retiredKeys,
+		AccessTokenTTL:               getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:              getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		RevocationCacheTTL:           getEnvDuration("REVOCATION_CACHE_TTL", 30*time.Second),
+		AppBaseURL:                   appBaseURL,
+		MailDir:                      getEnv("MAIL_DIR", ""),
+		MailFrom:                     getEnv("MAIL_FROM", "Book Exchange <no-reply@book-exchange.local>"),
+		PasswordResetTTL:             getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		EmailVerificationTTL:         getEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		VerificationResendCooldown:   getEnvDuration("VERIFICATION_RESEND_COOLDOWN", time.Minute),
+		VerificationResendDailyLimit: getEnvInt("VERIFICATION_RESEND_DAILY_LIMIT", 5),
+		TOTPIssuer:                   getEnv("TOTP_ISSUER", "Book Exchange"),
+		MFATokenTTL:                  getEnvDuration("MFA_TOKEN_TTL", 5*time.Minute),
+		LoginFreeAttempts:            getEnvInt("LOGIN_FREE_ATTEMPTS", 5),
+		LoginBackoffBase:             getEnvDuration("LOGIN_BACKOFF_BASE", time.Second),
+		LoginBackoffMax:              getEnvDuration("LOGIN_BACKOFF_MAX", 15*time.Minute),
+		AccountLockoutThreshold:      getEnvInt("ACCOUNT_LOCKOUT_THRESHOLD", 10),
+		IPLockoutThreshold:           getEnvInt("IP_LOCKOUT_THRESHOLD", 50),
+		LockoutDuration:              getEnvDuration("LOCKOUT_DURATION", 30*time.Minute),
+		AccountUnlockTTL:             getEnvDuration("ACCOUNT_UNLOCK_TTL", 24*time.Hour),
+		TrustProxyHeaders:            getEnvBool("TRUST_PROXY_HEADERS", false),
+		RequireVerifiedEmail:         getEnvBool("REQUIRE_VERIFIED_EMAIL", true),
+		PasswordHashAlgorithm:        getEnv("PASSWORD_HASH_ALGORITHM", "bcrypt"),
+		BcryptCost:                   getEnvInt("BCRYPT_COST", 10),
+		Argon2Memory:                 getEnvInt("ARGON2_MEMORY_KIB", 64*1024),
+		Argon2Iterations:             getEnvInt("ARGON2_ITERATIONS", 3),
+		Argon2Parallelism:            getEnvInt("ARGON2_PARALLELISM", 4),
+		AccountDeletionGracePeriod:   getEnvDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour),
+		OIDCProviders:                loadOIDCProviders(appBaseURL),
+		OIDCStateTTL:                 getEnvDuration("OIDC_STATE_TTL", 10*time.Minute),
+		StorageBackend:               getEnv("STORAGE_BACKEND", "local"),
+		StorageLocalDir:              getEnv("STORAGE_LOCAL_DIR", "uploads"),
+		S3Endpoint:                   getEnv("S3_ENDPOINT", ""),
+		S3Region:                     getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:                     getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:                getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretKey:                  getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3PublicURL:                  getEnv("S3_PUBLIC_URL", ""),
+		AvatarMaxBytes:               int64(getEnvInt("AVATAR_MAX_BYTES", 5<<20)),
+		AvatarSizes:                  getEnvIntList("AVATAR_SIZES", []int{64, 128, 256, 512}),
+		LocationPrecisionMeters:      getEnvInt("LOCATION_PRECISION_METERS", 1000),
+		MetadataProvider:             getEnv("METADATA_PROVIDER", "openlibrary"),
+		GoogleBooksAPIKey:            getEnv("GOOGLE_BOOKS_API_KEY", ""),
+		MetadataFixtures:             getEnv("METADATA_FIXTURES", ""),
+		MetadataTimeout:              getEnvDuration("METADATA_TIMEOUT", 5*time.Second),
+		MetadataCacheTTL:             getEnvDuration("METADATA_CACHE_TTL", 7*24*time.Hour),
+		MetadataCircuitThreshold:     getEnvInt("METADATA_CIRCUIT_THRESHOLD", 5),
+		MetadataCircuitCooldown:      getEnvDuration("METADATA_CIRCUIT_COOLDOWN", time.Minute),
+	}, nil
 }
 
 // getEnv retrieves environment variables with a fallback default value
@@ -36,3 +192,114 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvDuration parses a duration such as "15m" or "720h", falling back on
+// the default when the variable is unset or malformed
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+// getEnvInt parses an integer environment variable with a fallback default value
+func getEnvInt(key string, fallback int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// getEnvIntList parses a comma separated list of positive integers, falling
+// back on the default when the variable is unset or malformed
+func getEnvIntList(key string, fallback []int) []int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	var list []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || n <= 0 {
+			return fallback
+		}
+		list = append(list, n)
+	}
+	return list
+}
+
+// getEnvBool parses a boolean environment variable with a fallback default value
+func getEnvBool(key string, fallback bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
+// parseKeyConfig parses a "kid=path" pair; an empty value configures no key
+func parseKeyConfig(value string) (JWTKeyConfig, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return JWTKeyConfig{}, nil
+	}
+	kid, path, found := strings.Cut(value, "=")
+	kid, path = strings.TrimSpace(kid), strings.TrimSpace(path)
+	if !found || kid == "" || path == "" {
+		return JWTKeyConfig{}, fmt.Errorf("%q is not of the form kid=path", value)
+	}
+	return JWTKeyConfig{ID: kid, Path: path}, nil
+}
+
+// parseKeyConfigList parses a comma separated list of "kid=path" pairs
+func parseKeyConfigList(value string) ([]JWTKeyConfig, error) {
+	var keys []JWTKeyConfig
+	for _, item := range strings.Split(value, ",") {
+		key, err := parseKeyConfig(item)
+		if err != nil {
+			return nil, err
+		}
+		if key.ID != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// loadOIDCProviders reads the providers named in OIDC_PROVIDERS (comma
+// separated). Each provider NAME is configured by OIDC_<NAME>_ISSUER_URL,
+// OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET (empty for public clients),
+// OIDC_<NAME>_REDIRECT_URL and OIDC_<NAME>_SCOPES (space separated).
+func loadOIDCProviders(appBaseURL string) []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		providers = append(providers, OIDCProviderConfig{
+			Name:         name,
+			IssuerURL:    getEnv(prefix+"ISSUER_URL", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", appBaseURL+"/auth/oidc/"+name+"/callback"),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		})
+	}
+	return providers
+}
@@ -0,0 +1,187 @@
+// internal/delivery/router/handlers/account_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
+	"github.com/almatkai/book-exchange-backend/internal/usecase"
+)
+
+type AccountHandler struct {
+	accountUseCase usecase.AccountUseCase
+}
+
+func NewAccountHandler(accountUseCase usecase.AccountUseCase) *AccountHandler {
+	return &AccountHandler{accountUseCase}
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Email a password reset link if an account with this email exists. Always responds 202.
+// @Tags account
+// @Accept  json
+// @Param body body entity.ForgotPasswordRequest true "Account email"
+// @Success 202 "Accepted"
+// @Failure 400 {string} string "Invalid input"
+// @Failure 500 {string} string "Internal server error"
+// @Router /password/forgot [post]
+func (h *AccountHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req entity.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.accountUseCase.RequestPasswordReset(req.Email); err != nil {
+		http.Error(w, "Failed to request password reset", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword godoc
+// @Summary Reset a password
+// @Description Set a new password using the token from a password reset email. Logs out all sessions.
+// @Tags account
+// @Accept  json
+// @Param body body entity.ResetPasswordRequest true "Reset token and new password"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid input or token"
+// @Failure 500 {string} string "Internal server error"
+// @Router /password/reset [post]
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req entity.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" || req.NewPassword == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if !isValidPassword(req.NewPassword) {
+		http.Error(w, "Password does not meet strength requirements", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.accountUseCase.ResetPassword(req.Token, req.NewPassword); err != nil {
+		if err == usecase.ErrInvalidResetToken {
+			http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnlockAccount godoc
+// @Summary Unlock a locked account
+// @Description Lift a lockout caused by failed logins with the token from the lockout email. The token can be sent as a query parameter or in the body.
+// @Tags account
+// @Accept  json
+// @Param token query string false "Unlock token"
+// @Param body body entity.UnlockAccountRequest false "Unlock token"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid or expired unlock token"
+// @Failure 500 {string} string "Internal server error"
+// @Router /account/unlock [get]
+// @Router /account/unlock [post]
+func (h *AccountHandler) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	req := entity.UnlockAccountRequest{Token: r.URL.Query().Get("token")}
+	if req.Token == "" && r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.accountUseCase.UnlockAccount(req.Token); err != nil {
+		if err == usecase.ErrInvalidUnlockToken {
+			http.Error(w, "Invalid or expired unlock token", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to unlock account", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail godoc
+// @Summary Verify an email address
+// @Description Confirm the email address of an account with the token from the verification email. The token can be sent as a query parameter or in the body.
+// @Tags account
+// @Accept  json
+// @Param token query string false "Verification token"
+// @Param body body entity.VerifyEmailRequest false "Verification token"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid or expired verification token"
+// @Failure 500 {string} string "Internal server error"
+// @Router /verify-email [get]
+// @Router /verify-email [post]
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	req := entity.VerifyEmailRequest{Token: r.URL.Query().Get("token")}
+	if req.Token == "" && r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.accountUseCase.VerifyEmail(req.Token); err != nil {
+		if err == usecase.ErrInvalidVerificationToken {
+			http.Error(w, "Invalid or expired verification token", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerification godoc
+// @Summary Resend the verification email
+// @Description Send a new email verification link to the current user. Throttled per account.
+// @Tags account
+// @Security BearerAuth
+// @Success 202 "Accepted"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "Email already verified"
+// @Failure 429 {string} string "Too many requests"
+// @Failure 500 {string} string "Internal server error"
+// @Router /verify-email/resend [post]
+func (h *AccountHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.accountUseCase.ResendEmailVerification(user); err != nil {
+		switch err {
+		case usecase.ErrEmailAlreadyVerified:
+			http.Error(w, "Email already verified", http.StatusConflict)
+			return
+		case usecase.ErrTooManyRequests:
+			http.Error(w, "Too many requests, try again later", http.StatusTooManyRequests)
+			return
+		default:
+			http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
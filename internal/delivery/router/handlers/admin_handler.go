@@ -0,0 +1,234 @@
+// internal/delivery/router/handlers/admin_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
+	"github.com/almatkai/book-exchange-backend/internal/usecase"
+)
+
+type AdminHandler struct {
+	adminUseCase usecase.AdminUseCase
+}
+
+func NewAdminHandler(adminUseCase usecase.AdminUseCase) *AdminHandler {
+	return &AdminHandler{adminUseCase}
+}
+
+// ListUsers godoc
+// @Summary List accounts
+// @Description List all accounts with their roles. Requires users:read.
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} entity.Page[entity.UserWithRoles]
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal server error"
+// @Router /admin/users [get]
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+	page, err := h.adminUseCase.ListUsers(limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to list users", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// ListRoles godoc
+// @Summary List roles
+// @Description List all roles and the permissions they grant. Requires users:read.
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} entity.Role
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal server error"
+// @Router /admin/roles [get]
+func (h *AdminHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.adminUseCase.ListRoles()
+	if err != nil {
+		http.Error(w, "Failed to list roles", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, roles)
+}
+
+// SetUserRoles godoc
+// @Summary Replace a user's roles
+// @Description Assign exactly the given roles to a user and end their sessions. Requires users:manage_roles.
+// @Tags admin
+// @Accept  json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param body body entity.SetRolesRequest true "Roles"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid input or unknown role"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /admin/users/{id}/roles [put]
+func (h *AdminHandler) SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	actor, userID, ok := adminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req entity.SetRolesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.adminUseCase.SetUserRoles(actor, userID, req.Roles, clientInfo(r)); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAuditLogs godoc
+// @Summary Read the audit log
+// @Description List security audit entries, newest first. Requires audit:read.
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} entity.Page[entity.AuditLog]
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal server error"
+// @Router /admin/audit-logs [get]
+func (h *AdminHandler) ListAuditLogs(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+	page, err := h.adminUseCase.ListAuditLogs(limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to list audit logs", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// SuspendUser godoc
+// @Summary Suspend a user
+// @Description Deactivate an account and end all of its sessions. Requires users:suspend.
+// @Tags moderation
+// @Accept  json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param body body entity.SuspendRequest false "Reason"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /moderation/users/{id}/suspend [post]
+func (h *AdminHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	actor, userID, ok := adminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req entity.SuspendRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.adminUseCase.SuspendUser(actor, userID, req.Reason, clientInfo(r)); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnsuspendUser godoc
+// @Summary Reinstate a user
+// @Description Lift a suspension. An account its owner deactivated before the suspension stays deactivated. Requires users:suspend.
+// @Tags moderation
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 204 "No Content"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User not found"
+// @Failure 409 {string} string "Account is not suspended"
+// @Failure 500 {string} string "Internal server error"
+// @Router /moderation/users/{id}/unsuspend [post]
+func (h *AdminHandler) UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	actor, userID, ok := adminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.adminUseCase.UnsuspendUser(actor, userID, clientInfo(r)); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminTarget reads the acting user and the {id} path variable
+func adminTarget(w http.ResponseWriter, r *http.Request) (*entity.User, int, bool) {
+	actor, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, 0, false
+	}
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return nil, 0, false
+	}
+	return actor, userID, true
+}
+
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch err {
+	case usecase.ErrUserNotFound:
+		http.Error(w, "User not found", http.StatusNotFound)
+	case usecase.ErrRoleNotFound:
+		http.Error(w, "Unknown role", http.StatusBadRequest)
+	case usecase.ErrCannotModifySelf, usecase.ErrInsufficientRight:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case usecase.ErrNotSuspended:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+	}
+}
+
+// pageParams reads limit and offset query parameters; invalid values are
+// left at zero for the use case to default
+func pageParams(r *http.Request) (int, int) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	return limit, offset
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
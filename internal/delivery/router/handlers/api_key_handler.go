@@ -0,0 +1,137 @@
+// internal/delivery/router/handlers/api_key_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
+	"github.com/almatkai/book-exchange-backend/internal/usecase"
+)
+
+type APIKeyHandler struct {
+	apiKeyUseCase usecase.APIKeyUseCase
+}
+
+func NewAPIKeyHandler(apiKeyUseCase usecase.APIKeyUseCase) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyUseCase}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create a named API key limited to the given scopes (permission names). Send it as "Authorization: ApiKey <key>". The key is shown only once.
+// @Tags api-keys
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param body body entity.CreateAPIKeyRequest true "Name, scopes and optional lifetime"
+// @Success 201 {object} entity.CreatedAPIKey
+// @Failure 400 {string} string "Invalid name or scopes"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Requires a session login"
+// @Failure 409 {string} string "Too many API keys"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req entity.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ExpiresInDays < 0 {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	expiresIn := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	created, err := h.apiKeyUseCase.CreateAPIKey(user, req.Name, req.Scopes, expiresIn, clientInfo(r))
+	if err != nil {
+		switch err {
+		case usecase.ErrInvalidAPIKeyName, usecase.ErrInvalidScope:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case usecase.ErrTooManyAPIKeys:
+			http.Error(w, "Too many API keys, revoke one first", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List the current user's API keys that have not been revoked
+// @Tags api-keys
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} entity.APIKey
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Requires a session login"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.apiKeyUseCase.ListAPIKeys(user.UserID)
+	if err != nil {
+		http.Error(w, "Failed to list API keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Permanently disable one of the current user's API keys
+// @Tags api-keys
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 204 "No Content"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Requires a session login"
+// @Failure 404 {string} string "API key not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.apiKeyUseCase.RevokeAPIKey(user.UserID, id, clientInfo(r)); err != nil {
+		if err == usecase.ErrAPIKeyNotFound {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,111 @@
+// internal/delivery/router/handlers/block_handler.go
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
+	"github.com/almatkai/book-exchange-backend/internal/usecase"
+)
+
+type BlockHandler struct {
+	blockUseCase usecase.BlockUseCase
+}
+
+func NewBlockHandler(blockUseCase usecase.BlockUseCase) *BlockHandler {
+	return &BlockHandler{blockUseCase}
+}
+
+// Block godoc
+// @Summary Block a user
+// @Description Block a user. Neither of you can message, follow, rate or request exchanges from the other, and their content is hidden from you. Follows between you are removed.
+// @Tags social
+// @Security BearerAuth
+// @Param username path string true "Username"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "You cannot block yourself"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /users/{username}/block [put]
+func (h *BlockHandler) Block(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.blockUseCase.Block(user, mux.Vars(r)["username"]); err != nil {
+		writeBlockError(w, err, "Failed to block user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Unblock godoc
+// @Summary Unblock a user
+// @Tags social
+// @Security BearerAuth
+// @Param username path string true "Username"
+// @Success 204 "No Content"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /users/{username}/block [delete]
+func (h *BlockHandler) Unblock(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.blockUseCase.Unblock(user, mux.Vars(r)["username"]); err != nil {
+		writeBlockError(w, err, "Failed to unblock user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListBlocked godoc
+// @Summary List blocked users
+// @Description List the users you blocked, most recent first
+// @Tags social
+// @Produce  json
+// @Security BearerAuth
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} entity.Page[entity.PublicProfile]
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/blocks [get]
+func (h *BlockHandler) ListBlocked(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit, offset := pageParams(r)
+	page, err := h.blockUseCase.ListBlocked(user, limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to list blocked users", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+func writeBlockError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case usecase.ErrUserNotFound:
+		http.Error(w, "User not found", http.StatusNotFound)
+	case usecase.ErrCannotBlockSelf:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
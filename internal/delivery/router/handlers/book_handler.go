@@ -1 +1,411 @@
+// internal/delivery/router/handlers/book_handler.go
 package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
+	"github.com/almatkai/book-exchange-backend/internal/usecase"
+)
+
+type BookHandler struct {
+	bookUseCase usecase.BookUseCase
+}
+
+func NewBookHandler(bookUseCase usecase.BookUseCase) *BookHandler {
+	return &BookHandler{bookUseCase}
+}
+
+// CreateBook godoc
+// @Summary List a book
+// @Description Add a book to your library. It is available for exchange and placed at your approximate location. With an isbn, title and author may be left out and are filled in from the book catalog, along with a missing language, publication year and cover image.
+// @Tags books
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param book body entity.CreateBookRequest true "Book"
+// @Success 201 {object} entity.Book
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 503 {string} string "Book catalog unavailable"
+// @Router /books [post]
+func (h *BookHandler) CreateBook(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req entity.CreateBookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	book, err := h.bookUseCase.CreateBook(r.Context(), user, req)
+	if err != nil {
+		writeBookError(w, err, "Failed to create book")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, book)
+}
+
+// GetBook godoc
+// @Summary Get a book
+// @Tags books
+// @Produce  json
+// @Security BearerAuth
+// @Param id path int true "Book ID"
+// @Success 200 {object} entity.Book
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Book not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /books/{id} [get]
+func (h *BookHandler) GetBook(w http.ResponseWriter, r *http.Request) {
+	user, bookID, ok := bookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	book, err := h.bookUseCase.GetBook(user, bookID)
+	if err != nil {
+		writeBookError(w, err, "Failed to load book")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, book)
+}
+
+// ListMyBooks godoc
+// @Summary List own books
+// @Description List your books, available or not, newest first
+// @Tags books
+// @Produce  json
+// @Security BearerAuth
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} entity.Page[entity.Book]
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/books [get]
+func (h *BookHandler) ListMyBooks(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit, offset := pageParams(r)
+	page, err := h.bookUseCase.ListMyBooks(user, limit, offset)
+	if err != nil {
+		writeBookError(w, err, "Failed to list books")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// ListUserBooks godoc
+// @Summary List a user's books
+// @Description List the books of a user, newest first
+// @Tags books
+// @Produce  json
+// @Security BearerAuth
+// @Param username path string true "Username"
+// @Param available query bool false "Only books available for exchange"
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} entity.Page[entity.Book]
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "One of you blocked the other"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /users/{username}/books [get]
+func (h *BookHandler) ListUserBooks(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	availableOnly, _ := strconv.ParseBool(r.URL.Query().Get("available"))
+	limit, offset := pageParams(r)
+	page, err := h.bookUseCase.ListUserBooks(user, mux.Vars(r)["username"], availableOnly, limit, offset)
+	if err != nil {
+		writeBookError(w, err, "Failed to list books")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// FindBooksByISBN godoc
+// @Summary Find books by ISBN
+// @Description Available books with an ISBN. The ISBN may be given as ISBN-10 or ISBN-13, with or without hyphens.
+// @Tags books
+// @Produce  json
+// @Security BearerAuth
+// @Param isbn query string true "ISBN"
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} entity.Page[entity.Book]
+// @Failure 400 {string} string "Invalid ISBN"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /books [get]
+func (h *BookHandler) FindBooksByISBN(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit, offset := pageParams(r)
+	page, err := h.bookUseCase.FindByISBN(user, r.URL.Query().Get("isbn"), limit, offset)
+	if err != nil {
+		writeBookError(w, err, "Failed to search books")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// LookupMetadata godoc
+// @Summary Look up book details by ISBN
+// @Description Title, authors, cover, publication year, language and page count of an edition, from an external book catalog. Use it to prefill the listing form.
+// @Tags books
+// @Produce  json
+// @Security BearerAuth
+// @Param isbn query string true "ISBN-10 or ISBN-13"
+// @Success 200 {object} entity.BookMetadata
+// @Failure 400 {string} string "Invalid ISBN"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "No book details found"
+// @Failure 503 {string} string "Book catalog unavailable"
+// @Router /books/metadata [get]
+func (h *BookHandler) LookupMetadata(w http.ResponseWriter, r *http.Request) {
+	meta, err := h.bookUseCase.LookupMetadata(r.Context(), r.URL.Query().Get("isbn"))
+	if err != nil {
+		writeBookError(w, err, "Failed to look up book details")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, meta)
+}
+
+// UpdateBook godoc
+// @Summary Update a book
+// @Description Change the details of your book. Omitted fields are left unchanged; an empty isbn or a publication_year of 0 removes it. A new condition is added to the condition history.
+// @Tags books
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path int true "Book ID"
+// @Param book body entity.UpdateBookRequest true "Book fields"
+// @Success 200 {object} entity.Book
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Not the owner"
+// @Failure 404 {string} string "Book not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /books/{id} [patch]
+func (h *BookHandler) UpdateBook(w http.ResponseWriter, r *http.Request) {
+	user, bookID, ok := bookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req entity.UpdateBookRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, "Invalid input, only title, author, isbn, condition, description, genre, language, publication_year and image_url can be changed", http.StatusBadRequest)
+		return
+	}
+
+	book, err := h.bookUseCase.UpdateBook(user, bookID, req)
+	if err != nil {
+		writeBookError(w, err, "Failed to update book")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, book)
+}
+
+// SetAvailability godoc
+// @Summary Set book availability
+// @Description Offer your book for exchange or withdraw it
+// @Tags books
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path int true "Book ID"
+// @Param availability body object true "{\"is_available\": true}"
+// @Success 200 {object} entity.Book
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Not the owner"
+// @Failure 404 {string} string "Book not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /books/{id}/availability [put]
+func (h *BookHandler) SetAvailability(w http.ResponseWriter, r *http.Request) {
+	user, bookID, ok := bookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		IsAvailable *bool `json:"is_available"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.IsAvailable == nil {
+		http.Error(w, "Invalid input, is_available is required", http.StatusBadRequest)
+		return
+	}
+
+	book, err := h.bookUseCase.SetAvailability(user, bookID, *req.IsAvailable)
+	if err != nil {
+		writeBookError(w, err, "Failed to update book")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, book)
+}
+
+// DeleteBook godoc
+// @Summary Delete a book
+// @Description Remove your book. Books in a pending or accepted exchange cannot be deleted.
+// @Tags books
+// @Security BearerAuth
+// @Param id path int true "Book ID"
+// @Success 204 "No Content"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Not the owner"
+// @Failure 404 {string} string "Book not found"
+// @Failure 409 {string} string "Book is part of an open exchange"
+// @Failure 500 {string} string "Internal server error"
+// @Router /books/{id} [delete]
+func (h *BookHandler) DeleteBook(w http.ResponseWriter, r *http.Request) {
+	user, bookID, ok := bookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.bookUseCase.DeleteBook(user, bookID); err != nil {
+		writeBookError(w, err, "Failed to delete book")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RecordCondition godoc
+// @Summary Record a book's condition
+// @Description Set the condition of your book, with an optional note on what changed, e.g. "coffee stain on the cover". The change is added to the condition history.
+// @Tags books
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param id path int true "Book ID"
+// @Param condition body entity.RecordConditionRequest true "Condition"
+// @Success 201 {object} entity.BookConditionChange
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Not the owner"
+// @Failure 404 {string} string "Book not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /books/{id}/condition [post]
+func (h *BookHandler) RecordCondition(w http.ResponseWriter, r *http.Request) {
+	user, bookID, ok := bookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req entity.RecordConditionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	change, err := h.bookUseCase.RecordCondition(user, bookID, req)
+	if err != nil {
+		writeBookError(w, err, "Failed to record condition")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, change)
+}
+
+// ConditionHistory godoc
+// @Summary List a book's condition history
+// @Description The conditions a book was in since it was listed, newest first
+// @Tags books
+// @Produce  json
+// @Security BearerAuth
+// @Param id path int true "Book ID"
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} entity.Page[entity.BookConditionChange]
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Book not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /books/{id}/condition [get]
+func (h *BookHandler) ConditionHistory(w http.ResponseWriter, r *http.Request) {
+	user, bookID, ok := bookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset := pageParams(r)
+	page, err := h.bookUseCase.ConditionHistory(user, bookID, limit, offset)
+	if err != nil {
+		writeBookError(w, err, "Failed to load condition history")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// bookRequest reads the authenticated user and the book ID from the path
+func bookRequest(w http.ResponseWriter, r *http.Request) (*entity.User, int, bool) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, 0, false
+	}
+	bookID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid book id", http.StatusBadRequest)
+		return nil, 0, false
+	}
+	return user, bookID, true
+}
+
+func writeBookError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case usecase.ErrBookNotFound:
+		http.Error(w, "Book not found", http.StatusNotFound)
+	case usecase.ErrUserNotFound:
+		http.Error(w, "User not found", http.StatusNotFound)
+	case usecase.ErrNotBookOwner, usecase.ErrBlocked:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case usecase.ErrBookInExchange:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case usecase.ErrMetadataNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case usecase.ErrMetadataUnavailable:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case usecase.ErrInvalidTitle, usecase.ErrInvalidAuthor, usecase.ErrInvalidISBN, usecase.ErrInvalidCondition,
+		usecase.ErrInvalidDescription, usecase.ErrInvalidGenre, usecase.ErrInvalidLanguage, usecase.ErrInvalidYear,
+		usecase.ErrInvalidImageURL, usecase.ErrInvalidNote, usecase.ErrEmptyBookUpdate:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,162 @@
+// internal/delivery/router/handlers/follow_handler.go
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
+	"github.com/almatkai/book-exchange-backend/internal/usecase"
+)
+
+type FollowHandler struct {
+	followUseCase usecase.FollowUseCase
+}
+
+func NewFollowHandler(followUseCase usecase.FollowUseCase) *FollowHandler {
+	return &FollowHandler{followUseCase}
+}
+
+// Follow godoc
+// @Summary Follow a user
+// @Description Follow a user to see their activity in the feed. Following someone twice has no effect.
+// @Tags social
+// @Security BearerAuth
+// @Param username path string true "Username"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "You cannot follow yourself"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "One of you blocked the other"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /users/{username}/follow [put]
+func (h *FollowHandler) Follow(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.followUseCase.Follow(user, mux.Vars(r)["username"]); err != nil {
+		writeFollowError(w, err, "Failed to follow user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Unfollow godoc
+// @Summary Unfollow a user
+// @Tags social
+// @Security BearerAuth
+// @Param username path string true "Username"
+// @Success 204 "No Content"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /users/{username}/follow [delete]
+func (h *FollowHandler) Unfollow(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.followUseCase.Unfollow(user, mux.Vars(r)["username"]); err != nil {
+		writeFollowError(w, err, "Failed to unfollow user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListFollowers godoc
+// @Summary List followers
+// @Description List the users following a user, most recent first
+// @Tags social
+// @Produce  json
+// @Param username path string true "Username"
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} entity.Page[entity.PublicProfile]
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /users/{username}/followers [get]
+func (h *FollowHandler) ListFollowers(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+	page, err := h.followUseCase.ListFollowers(mux.Vars(r)["username"], limit, offset)
+	if err != nil {
+		writeFollowError(w, err, "Failed to list followers")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// ListFollowing godoc
+// @Summary List followed users
+// @Description List the users a user follows, most recent first
+// @Tags social
+// @Produce  json
+// @Param username path string true "Username"
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} entity.Page[entity.PublicProfile]
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /users/{username}/following [get]
+func (h *FollowHandler) ListFollowing(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+	page, err := h.followUseCase.ListFollowing(mux.Vars(r)["username"], limit, offset)
+	if err != nil {
+		writeFollowError(w, err, "Failed to list followed users")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// Feed godoc
+// @Summary Activity feed
+// @Description New listings, reviews and completed exchanges of the users you follow, newest first. Pass next_cursor as cursor to get the next page.
+// @Tags social
+// @Produce  json
+// @Security BearerAuth
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Page size (max 50)"
+// @Success 200 {object} entity.FeedPage
+// @Failure 400 {string} string "Invalid cursor"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /feed [get]
+func (h *FollowHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	page, err := h.followUseCase.Feed(user, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		writeFollowError(w, err, "Failed to load feed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+func writeFollowError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case usecase.ErrUserNotFound:
+		http.Error(w, "User not found", http.StatusNotFound)
+	case usecase.ErrCannotFollowSelf, usecase.ErrInvalidCursor:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case usecase.ErrBlocked:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,172 @@
+// internal/delivery/router/handlers/import_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
+	"github.com/almatkai/book-exchange-backend/internal/usecase"
+)
+
+// importMaxBytes limits the size of an uploaded import file
+const importMaxBytes = 5 << 20
+
+type ImportHandler struct {
+	importUseCase usecase.BookImportUseCase
+}
+
+func NewImportHandler(importUseCase usecase.BookImportUseCase) *ImportHandler {
+	return &ImportHandler{importUseCase}
+}
+
+// StartImport godoc
+// @Summary Import books
+// @Description Add many books at once from a CSV file with title, author and optionally isbn, condition, description, genre, language, publication_year and image_url columns, or from a Goodreads library export. The file is checked right away and imported in the background; poll the returned job for progress. Books whose ISBN is already in your library are skipped, as are Goodreads books on the to-read shelf. Rows without a condition are listed as Good.
+// @Tags books
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security BearerAuth
+// @Param file formData file true "CSV file"
+// @Success 202 {object} entity.ImportJob
+// @Failure 400 {string} string "Invalid file"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "An import is already in progress"
+// @Failure 413 {string} string "File too large"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/imports [post]
+func (h *ImportHandler) StartImport(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data, err := readImportFile(w, r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("File too large, the limit is %d bytes", importMaxBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Expected a multipart form with a file", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.importUseCase.StartImport(user, data)
+	if err != nil {
+		writeImportError(w, err, "Failed to start import")
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// readImportFile returns the content of the "file" part of a multipart upload
+func readImportFile(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, importMaxBytes+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(part, importMaxBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > importMaxBytes {
+			return nil, &http.MaxBytesError{Limit: importMaxBytes}
+		}
+		return data, nil
+	}
+}
+
+// GetImport godoc
+// @Summary Get an import
+// @Description Get the progress of an import and the rows that were skipped or failed
+// @Tags books
+// @Produce  json
+// @Security BearerAuth
+// @Param id path string true "Import ID"
+// @Success 200 {object} entity.ImportJob
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Import not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/imports/{id} [get]
+func (h *ImportHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Import not found", http.StatusNotFound)
+		return
+	}
+
+	job, err := h.importUseCase.GetImport(user, id)
+	if err != nil {
+		writeImportError(w, err, "Failed to load import")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// ListImports godoc
+// @Summary List imports
+// @Description List your imports, newest first. Row errors are only included when getting a single import.
+// @Tags books
+// @Produce  json
+// @Security BearerAuth
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} entity.Page[entity.ImportJob]
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/imports [get]
+func (h *ImportHandler) ListImports(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit, offset := pageParams(r)
+	page, err := h.importUseCase.ListImports(user, limit, offset)
+	if err != nil {
+		writeImportError(w, err, "Failed to list imports")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+func writeImportError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case usecase.ErrImportNotFound:
+		http.Error(w, "Import not found", http.StatusNotFound)
+	case usecase.ErrImportInProgress:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case usecase.ErrImportTooLarge:
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case usecase.ErrInvalidImportFile, usecase.ErrEmptyImport:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
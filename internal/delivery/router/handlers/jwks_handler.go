@@ -0,0 +1,31 @@
+// internal/delivery/router/handlers/jwks_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/almatkai/book-exchange-backend/pkg/utils"
+)
+
+type JWKSHandler struct {
+	jwtSvc utils.JWTService
+}
+
+func NewJWKSHandler(jwtSvc utils.JWTService) *JWKSHandler {
+	return &JWKSHandler{jwtSvc}
+}
+
+// GetJWKS godoc
+// @Summary Public signing keys
+// @Description Return the public keys access tokens can be verified with, as a JSON Web Key Set
+// @Tags auth
+// @Produce  json
+// @Success 200 {object} utils.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.jwtSvc.JWKS())
+}
@@ -0,0 +1,192 @@
+// internal/delivery/router/handlers/mfa_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
+	"github.com/almatkai/book-exchange-backend/internal/usecase"
+)
+
+type MFAHandler struct {
+	mfaUseCase usecase.MFAUseCase
+}
+
+func NewMFAHandler(mfaUseCase usecase.MFAUseCase) *MFAHandler {
+	return &MFAHandler{mfaUseCase}
+}
+
+// EnrollTOTP godoc
+// @Summary Start TOTP enrollment
+// @Description Generate a TOTP secret for the current user, returned with an otpauth URI and a QR code PNG. Two-factor authentication is enabled only after confirmation.
+// @Tags mfa
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} entity.TOTPEnrollment
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "Two-factor authentication is already enabled"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/2fa/totp/enroll [post]
+func (h *MFAHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	enrollment, err := h.mfaUseCase.BeginTOTPEnrollment(user)
+	if err != nil {
+		if err == usecase.ErrTOTPAlreadyEnabled {
+			http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to start enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm TOTP enrollment
+// @Description Enable two-factor authentication with a code from the authenticator app and return one-time recovery codes. The codes are shown only once.
+// @Tags mfa
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param body body entity.MFACodeRequest true "TOTP code"
+// @Success 200 {object} entity.RecoveryCodesResponse
+// @Failure 400 {string} string "Invalid input or code"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "Two-factor authentication is already enabled"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/2fa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	user, req, ok := decodeMFACodeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.mfaUseCase.ConfirmTOTPEnrollment(user, req.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	writeRecoveryCodes(w, codes)
+}
+
+// DisableTOTP godoc
+// @Summary Disable TOTP
+// @Description Turn off two-factor authentication. Requires the current password and a current TOTP code or a recovery code. Failures count towards the login lockout.
+// @Tags mfa
+// @Accept  json
+// @Security BearerAuth
+// @Param body body entity.MFAChangeRequest true "Password and TOTP or recovery code"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid input or code"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Password is incorrect"
+// @Failure 423 {string} string "Account temporarily locked"
+// @Failure 429 {string} string "Too many failed attempts"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/2fa/totp/disable [post]
+func (h *MFAHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	user, req, ok := decodeMFAChangeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.mfaUseCase.DisableTOTP(user, req.Password, req.Code, clientInfo(r)); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes. Requires the current password and a current TOTP code or a recovery code. Failures count towards the login lockout.
+// @Tags mfa
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param body body entity.MFAChangeRequest true "Password and TOTP or recovery code"
+// @Success 200 {object} entity.RecoveryCodesResponse
+// @Failure 400 {string} string "Invalid input or code"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Password is incorrect"
+// @Failure 423 {string} string "Account temporarily locked"
+// @Failure 429 {string} string "Too many failed attempts"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/2fa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user, req, ok := decodeMFAChangeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.mfaUseCase.RegenerateRecoveryCodes(user, req.Password, req.Code, clientInfo(r))
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	writeRecoveryCodes(w, codes)
+}
+
+func decodeMFACodeRequest(w http.ResponseWriter, r *http.Request) (*entity.User, *entity.MFACodeRequest, bool) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	var req entity.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return user, &req, true
+}
+
+func decodeMFAChangeRequest(w http.ResponseWriter, r *http.Request) (*entity.User, *entity.MFAChangeRequest, bool) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	var req entity.MFAChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" || req.Code == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return user, &req, true
+}
+
+func writeMFAError(w http.ResponseWriter, err error) {
+	switch err {
+	case usecase.ErrInvalidMFACode:
+		http.Error(w, "Invalid two-factor code", http.StatusBadRequest)
+	case usecase.ErrTOTPNotEnrolled:
+		http.Error(w, "Two-factor authentication is not set up", http.StatusBadRequest)
+	case usecase.ErrTOTPAlreadyEnabled:
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+	default:
+		writePasswordConfirmationError(w, err, "Failed to update two-factor authentication")
+	}
+}
+
+func writeRecoveryCodes(w http.ResponseWriter, codes []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entity.RecoveryCodesResponse{RecoveryCodes: codes})
+}
@@ -0,0 +1,158 @@
+// internal/delivery/router/handlers/nearby_handler.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
+	"github.com/almatkai/book-exchange-backend/internal/usecase"
+)
+
+var errInvalidNearbyQuery = errors.New("invalid query: lat and lng must be given together, radius_km must be a number and bbox must be min_lng,min_lat,max_lng,max_lat")
+
+type NearbyHandler struct {
+	nearbyUseCase usecase.NearbyUseCase
+}
+
+func NewNearbyHandler(nearbyUseCase usecase.NearbyUseCase) *NearbyHandler {
+	return &NearbyHandler{nearbyUseCase}
+}
+
+// NearbyBooks godoc
+// @Summary Find books nearby
+// @Description Available books within radius_km of a position, or inside bbox, closest first. Without lat and lng your own location is used. Book positions are approximate.
+// @Tags discovery
+// @Produce  json
+// @Security BearerAuth
+// @Param lat query number false "Latitude"
+// @Param lng query number false "Longitude"
+// @Param radius_km query number false "Search radius in km (default 5, max 100)"
+// @Param bbox query string false "Bounding box min_lng,min_lat,max_lng,max_lat; replaces the radius"
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} entity.Page[entity.NearbyBook]
+// @Failure 400 {string} string "Invalid query"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /nearby/books [get]
+func (h *NearbyHandler) NearbyBooks(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query, err := parseNearbyQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := h.nearbyUseCase.NearbyBooks(user, *query)
+	if err != nil {
+		writeNearbyError(w, err, "Failed to search books")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// NearbyPosts godoc
+// @Summary Find posts nearby
+// @Description Published posts within radius_km of a position, or inside bbox, closest first. Without lat and lng your own location is used. Post positions are approximate.
+// @Tags discovery
+// @Produce  json
+// @Security BearerAuth
+// @Param lat query number false "Latitude"
+// @Param lng query number false "Longitude"
+// @Param radius_km query number false "Search radius in km (default 5, max 100)"
+// @Param bbox query string false "Bounding box min_lng,min_lat,max_lng,max_lat; replaces the radius"
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} entity.Page[entity.NearbyPost]
+// @Failure 400 {string} string "Invalid query"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /nearby/posts [get]
+func (h *NearbyHandler) NearbyPosts(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query, err := parseNearbyQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := h.nearbyUseCase.NearbyPosts(user, *query)
+	if err != nil {
+		writeNearbyError(w, err, "Failed to search posts")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// parseNearbyQuery reads lat, lng, radius_km, bbox, limit and offset
+func parseNearbyQuery(r *http.Request) (*entity.NearbyQuery, error) {
+	values := r.URL.Query()
+	query := &entity.NearbyQuery{}
+	query.Limit, query.Offset = pageParams(r)
+
+	lat, lng := values.Get("lat"), values.Get("lng")
+	if lat != "" || lng != "" {
+		latitude, err := strconv.ParseFloat(lat, 64)
+		if err != nil {
+			return nil, errInvalidNearbyQuery
+		}
+		longitude, err := strconv.ParseFloat(lng, 64)
+		if err != nil {
+			return nil, errInvalidNearbyQuery
+		}
+		query.Center = &entity.Coordinates{Latitude: latitude, Longitude: longitude}
+	}
+
+	if radius := values.Get("radius_km"); radius != "" {
+		km, err := strconv.ParseFloat(radius, 64)
+		if err != nil {
+			return nil, errInvalidNearbyQuery
+		}
+		query.RadiusKm = km
+	}
+
+	if bbox := values.Get("bbox"); bbox != "" {
+		parts := strings.Split(bbox, ",")
+		if len(parts) != 4 {
+			return nil, errInvalidNearbyQuery
+		}
+		var edges [4]float64
+		for i, part := range parts {
+			edge, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return nil, errInvalidNearbyQuery
+			}
+			edges[i] = edge
+		}
+		query.Bounds = &entity.BoundingBox{
+			MinLongitude: edges[0],
+			MinLatitude:  edges[1],
+			MaxLongitude: edges[2],
+			MaxLatitude:  edges[3],
+		}
+	}
+	return query, nil
+}
+
+func writeNearbyError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case usecase.ErrInvalidCoordinates, usecase.ErrInvalidRadius, usecase.ErrBoundingBoxTooLarge, usecase.ErrLocationRequired:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
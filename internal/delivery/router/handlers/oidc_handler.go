@@ -0,0 +1,229 @@
+// internal/delivery/router/handlers/oidc_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
+	"github.com/almatkai/book-exchange-backend/internal/usecase"
+)
+
+// oidcStateCookie holds the state of the authorization request the browser
+// started, which the callback requires
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	oidcUseCase usecase.OIDCUseCase
+}
+
+func NewOIDCHandler(oidcUseCase usecase.OIDCUseCase) *OIDCHandler {
+	return &OIDCHandler{oidcUseCase}
+}
+
+// ListProviders godoc
+// @Summary List identity providers
+// @Description Names of the configured OpenID Connect providers
+// @Tags auth
+// @Produce  json
+// @Success 200 {array} string
+// @Router /auth/oidc/providers [get]
+func (h *OIDCHandler) ListProviders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.oidcUseCase.Providers())
+}
+
+// Login godoc
+// @Summary Sign in with an identity provider
+// @Description Redirect the browser to the provider's authorization endpoint (authorization code flow with PKCE). Sets a cookie the callback requires, so the flow must finish in the same browser.
+// @Tags auth
+// @Param provider path string true "Provider name"
+// @Success 302 "Redirect to the provider"
+// @Failure 404 {string} string "Unknown provider"
+// @Failure 502 {string} string "Provider unavailable"
+// @Router /auth/oidc/{provider}/login [get]
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	provider := mux.Vars(r)["provider"]
+	authURL, state, err := h.oidcUseCase.BeginLogin(r.Context(), provider, nil)
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+
+	setOIDCStateCookie(w, r, provider, state)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback godoc
+// @Summary Identity provider callback
+// @Description Complete a sign-in or link request started at the provider. The browser must present the cookie set when the request was started. Sign-ins return the same result as /login; link requests return the linked identity.
+// @Tags auth
+// @Produce  json
+// @Param provider path string true "Provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} entity.LoginResult
+// @Failure 400 {string} string "Invalid or expired login state, or started in another browser"
+// @Failure 401 {string} string "Sign-in was denied or failed"
+// @Failure 403 {string} string "Account is disabled"
+// @Failure 404 {string} string "Unknown provider"
+// @Failure 409 {string} string "Email or identity already in use"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 502 {string} string "Sign-in with the provider failed"
+// @Router /auth/oidc/{provider}/callback [get]
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	provider := mux.Vars(r)["provider"]
+	var browserState string
+	if cookie, err := r.Cookie(oidcStateCookie); err == nil {
+		browserState = cookie.Value
+	}
+	// The state is single use, whatever the outcome
+	clearOIDCStateCookie(w, r, provider)
+
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		http.Error(w, "Sign-in was denied at the identity provider", http.StatusUnauthorized)
+		return
+	}
+	state, code := query.Get("state"), query.Get("code")
+	if state == "" || code == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	result, identity, err := h.oidcUseCase.HandleCallback(r.Context(), provider, state, browserState, code, clientInfo(r))
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if identity != nil {
+		json.NewEncoder(w).Encode(identity)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// LinkIdentity godoc
+// @Summary Link an identity provider
+// @Description Start linking a provider account to the current user. The response sets a cookie the callback requires, so call this from the browser (with credentials) and navigate that browser to the returned URL; the provider redirects back to the callback, which links the identity.
+// @Tags auth
+// @Produce  json
+// @Security BearerAuth
+// @Param provider path string true "Provider name"
+// @Success 200 {object} entity.OIDCRedirect
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Unknown provider"
+// @Failure 502 {string} string "Provider unavailable"
+// @Router /me/identities/{provider} [post]
+func (h *OIDCHandler) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	provider := mux.Vars(r)["provider"]
+	authURL, state, err := h.oidcUseCase.BeginLogin(r.Context(), provider, &user.UserID)
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+
+	setOIDCStateCookie(w, r, provider, state)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entity.OIDCRedirect{AuthorizationURL: authURL})
+}
+
+// ListIdentities godoc
+// @Summary List linked identities
+// @Description Identity provider accounts linked to the current user
+// @Tags auth
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} entity.ExternalIdentity
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/identities [get]
+func (h *OIDCHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	identities, err := h.oidcUseCase.ListIdentities(user.UserID)
+	if err != nil {
+		http.Error(w, "Failed to list identities", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(identities)
+}
+
+// setOIDCStateCookie binds an authorization request to the browser that
+// starts it. Lax cookies are still sent on the provider's redirect back.
+func setOIDCStateCookie(w http.ResponseWriter, r *http.Request, provider, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     oidcCallbackPath(provider),
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearOIDCStateCookie(w http.ResponseWriter, r *http.Request, provider string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     oidcCallbackPath(provider),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func oidcCallbackPath(provider string) string {
+	return "/auth/oidc/" + url.PathEscape(provider) + "/callback"
+}
+
+// isHTTPS reports whether the client reached us over TLS, directly or
+// through a proxy
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+func writeOIDCError(w http.ResponseWriter, err error) {
+	switch {
+	case err == usecase.ErrUnknownProvider:
+		http.Error(w, "Unknown identity provider", http.StatusNotFound)
+	case err == usecase.ErrInvalidLoginState:
+		http.Error(w, "Invalid or expired login state, please start again", http.StatusBadRequest)
+	case err == usecase.ErrAccountDisabled:
+		http.Error(w, "Account is disabled", http.StatusForbidden)
+	case err == usecase.ErrExternalEmailRequired:
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case err == usecase.ErrExternalEmailConflict, err == usecase.ErrIdentityLinked:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, usecase.ErrExternalLoginFailed):
+		log.Printf("oidc: %v", err)
+		http.Error(w, "Sign-in with the identity provider failed", http.StatusBadGateway)
+	default:
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+	}
+}
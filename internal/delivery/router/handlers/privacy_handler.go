@@ -0,0 +1,112 @@
+// internal/delivery/router/handlers/privacy_handler.go
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
+	"github.com/almatkai/book-exchange-backend/internal/usecase"
+)
+
+type PrivacyHandler struct {
+	privacyUseCase usecase.PrivacyUseCase
+}
+
+func NewPrivacyHandler(privacyUseCase usecase.PrivacyUseCase) *PrivacyHandler {
+	return &PrivacyHandler{privacyUseCase}
+}
+
+// ExportData godoc
+// @Summary Export personal data
+// @Description Download everything stored about the current user, as a ZIP archive with one JSON file per section (default) or as a single JSON document
+// @Tags account
+// @Produce  application/zip
+// @Produce  json
+// @Security BearerAuth
+// @Param format query string false "zip or json" Enums(zip, json)
+// @Success 200 {object} entity.DataExport
+// @Failure 400 {string} string "Unknown format"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/export [get]
+func (h *PrivacyHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "zip"
+	}
+	if format != "zip" && format != "json" {
+		http.Error(w, "Unknown format, use zip or json", http.StatusBadRequest)
+		return
+	}
+
+	export, err := h.privacyUseCase.ExportData(user)
+	if err != nil {
+		http.Error(w, "Failed to export data", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("book-exchange-%s-%s", user.Username, export.GeneratedAt.Format("20060102"))
+	w.Header().Set("Cache-Control", "no-store")
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(export)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+	w.WriteHeader(http.StatusOK)
+	// Headers are gone by now; a failure can only truncate the download
+	if err := writeExportZip(w, export); err != nil {
+		log.Printf("failed to write data export for user %d: %v", user.UserID, err)
+	}
+}
+
+// writeExportZip writes profile.json and one <section>.json per data section
+func writeExportZip(w io.Writer, export *entity.DataExport) error {
+	archive := zip.NewWriter(w)
+
+	files := map[string]interface{}{
+		"profile.json": struct {
+			GeneratedAt interface{}  `json:"generated_at"`
+			Profile     *entity.User `json:"profile"`
+		}{export.GeneratedAt, export.Profile},
+	}
+	for section, rows := range export.Data {
+		files[section+".json"] = rows
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: export.GeneratedAt})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(files[name]); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,299 @@
+// internal/delivery/router/handlers/profile_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
+	"github.com/almatkai/book-exchange-backend/internal/usecase"
+)
+
+// avatarTypes are the accepted avatar formats, as sniffed from the content
+var avatarTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// multipartOverhead allows for part headers and boundaries around the file
+const multipartOverhead = 64 << 10
+
+type ProfileHandler struct {
+	profileUseCase usecase.ProfileUseCase
+	avatarMaxBytes int64
+}
+
+func NewProfileHandler(profileUseCase usecase.ProfileUseCase, avatarMaxBytes int64) *ProfileHandler {
+	return &ProfileHandler{profileUseCase, avatarMaxBytes}
+}
+
+// GetMe godoc
+// @Summary Get own profile
+// @Description Get the current user's account, including private fields such as the email address
+// @Tags profile
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} entity.User
+// @Failure 401 {string} string "Unauthorized"
+// @Router /me [get]
+func (h *ProfileHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateMe godoc
+// @Summary Update own profile
+// @Description Change full_name, location and bio. Omitted fields are left unchanged; an empty string clears a field.
+// @Tags profile
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param profile body entity.UpdateProfileRequest true "Profile fields"
+// @Success 200 {object} entity.User
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me [patch]
+func (h *ProfileHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req entity.UpdateProfileRequest
+	decoder := json.NewDecoder(r.Body)
+	// Reject attempts to change fields such as the email address here
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, "Invalid input, only full_name, location and bio can be changed", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.profileUseCase.UpdateProfile(user, req)
+	if err != nil {
+		switch err {
+		case usecase.ErrInvalidFullName, usecase.ErrInvalidLocation, usecase.ErrInvalidBio, usecase.ErrEmptyProfileUpdate:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updated)
+}
+
+// GetUserProfile godoc
+// @Summary Get a user's public profile
+// @Description Get the public profile of an active user. The email address and account details are not included.
+// @Tags profile
+// @Produce  json
+// @Param username path string true "Username"
+// @Success 200 {object} entity.PublicProfile
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /users/{username} [get]
+func (h *ProfileHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := h.profileUseCase.GetPublicProfile(mux.Vars(r)["username"])
+	if err != nil {
+		if err == usecase.ErrUserNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+// UploadAvatar godoc
+// @Summary Upload avatar
+// @Description Replace the current user's avatar. The image is cropped to a square and stored as JPEG thumbnails in several sizes; metadata such as EXIF is removed.
+// @Tags profile
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security BearerAuth
+// @Param avatar formData file true "JPEG, PNG, GIF or WebP image"
+// @Success 200 {object} entity.User
+// @Failure 400 {string} string "Invalid image"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 413 {string} string "File too large"
+// @Failure 415 {string} string "Unsupported file type"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/avatar [put]
+func (h *ProfileHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data, err := h.readAvatar(w, r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("File too large, the limit is %d bytes", h.avatarMaxBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Expected a multipart form with an avatar file", http.StatusBadRequest)
+		return
+	}
+	if !avatarTypes[http.DetectContentType(data)] {
+		http.Error(w, "Unsupported file type, use JPEG, PNG, GIF or WebP", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	updated, err := h.profileUseCase.UploadAvatar(r.Context(), user, data)
+	if err != nil {
+		switch err {
+		case usecase.ErrInvalidImage, usecase.ErrImageTooLarge:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to store avatar", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updated)
+}
+
+// readAvatar returns the content of the "avatar" part of a multipart upload
+func (h *ProfileHandler) readAvatar(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.avatarMaxBytes+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() != "avatar" {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(part, h.avatarMaxBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > h.avatarMaxBytes {
+			return nil, &http.MaxBytesError{Limit: h.avatarMaxBytes}
+		}
+		return data, nil
+	}
+}
+
+// DeleteAvatar godoc
+// @Summary Delete avatar
+// @Description Remove the current user's avatar
+// @Tags profile
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/avatar [delete]
+func (h *ProfileHandler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.profileUseCase.DeleteAvatar(r.Context(), user); err != nil {
+		http.Error(w, "Failed to delete avatar", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetLocation godoc
+// @Summary Set own location
+// @Description Set the current user's position. Other users only see it snapped to a coarse grid, on your books and posts in nearby searches.
+// @Tags profile
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param location body entity.Coordinates true "Position in decimal degrees"
+// @Success 200 {object} entity.User
+// @Failure 400 {string} string "Invalid coordinates"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/location [put]
+func (h *ProfileHandler) SetLocation(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Latitude == nil || req.Longitude == nil {
+		http.Error(w, "Invalid input, latitude and longitude are required", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.profileUseCase.SetLocation(user, entity.Coordinates{Latitude: *req.Latitude, Longitude: *req.Longitude})
+	if err != nil {
+		if err == usecase.ErrInvalidCoordinates {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to set location", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updated)
+}
+
+// ClearLocation godoc
+// @Summary Clear own location
+// @Description Forget the current user's position. Your books and posts no longer appear in nearby searches.
+// @Tags profile
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/location [delete]
+func (h *ProfileHandler) ClearLocation(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.profileUseCase.ClearLocation(user); err != nil {
+		http.Error(w, "Failed to clear location", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
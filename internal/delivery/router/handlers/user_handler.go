@@ -3,9 +3,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 
 	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
 	"github.com/almatkai/book-exchange-backend/internal/usecase"
 
 	"unicode"
@@ -44,6 +52,12 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// "@" is reserved so that logins by username and by email cannot collide
+	if strings.Contains(creds.Username, "@") {
+		http.Error(w, "Username cannot contain @", http.StatusBadRequest)
+		return
+	}
+
 	// Password strength validation
 	if !isValidPassword(creds.Password) {
 		http.Error(w, "Password does not meet strength requirements", http.StatusBadRequest)
@@ -76,14 +90,16 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 // Login godoc
 // @Summary Login a user
-// @Description Authenticate user and return a JWT token
+// @Description Authenticate user by username or email (case-insensitive) and return a short-lived access token with a refresh token. Accounts with two-factor authentication instead receive mfa_required and an mfa_token for /login/mfa.
 // @Tags auth
 // @Accept  json
 // @Produce  json
 // @Param user body entity.UserCredentials true "User Credentials"
-// @Success 200 {object} map[string]string
+// @Success 200 {object} entity.LoginResult
 // @Failure 400 {string} string "Invalid input"
 // @Failure 401 {string} string "Invalid username or password"
+// @Failure 423 {string} string "Account temporarily locked"
+// @Failure 429 {string} string "Too many failed login attempts"
 // @Failure 500 {string} string "Internal server error"
 // @Router /login [post]
 func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -94,28 +110,433 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Input validation
-	if creds.Username == "" || creds.Password == "" {
-		http.Error(w, "Username and password are required", http.StatusBadRequest)
+	login := creds.Login()
+	if login == "" || creds.Password == "" {
+		http.Error(w, "Username or email and password are required", http.StatusBadRequest)
 		return
 	}
 
 	// Login user
-	token, err := h.userUseCase.LoginUser(creds.Username, creds.Password)
+	result, err := h.userUseCase.LoginUser(login, creds.Password, clientInfo(r))
 	if err != nil {
-		if err == usecase.ErrInvalidCredentials {
+		var throttled *usecase.ThrottledError
+		switch {
+		case err == usecase.ErrInvalidCredentials:
 			http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		case err == usecase.ErrAccountLocked:
+			http.Error(w, "Account temporarily locked, check your email to unlock it", http.StatusLocked)
+		case errors.As(err, &throttled):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+		default:
+			http.Error(w, "Failed to login", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Return tokens
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// LoginMFA godoc
+// @Summary Complete a two-factor login
+// @Description Exchange the mfa_token from /login and a TOTP or recovery code for a token pair. Each mfa_token allows a single attempt.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param body body entity.MFALoginRequest true "MFA token and code"
+// @Success 200 {object} entity.TokenPair
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Invalid token or code"
+// @Failure 423 {string} string "Account temporarily locked"
+// @Failure 429 {string} string "Too many failed login attempts"
+// @Failure 500 {string} string "Internal server error"
+// @Router /login/mfa [post]
+func (h *UserHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req entity.MFALoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" || req.Code == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.userUseCase.CompleteMFALogin(req.MFAToken, req.Code, clientInfo(r))
+	if err != nil {
+		var throttled *usecase.ThrottledError
+		switch {
+		case err == usecase.ErrInvalidMFAToken:
+			http.Error(w, "Invalid or expired mfa token", http.StatusUnauthorized)
+		case err == usecase.ErrInvalidMFACode, err == usecase.ErrTOTPNotEnrolled:
+			http.Error(w, "Invalid two-factor code", http.StatusUnauthorized)
+		case err == usecase.ErrAccountLocked:
+			http.Error(w, "Account temporarily locked, check your email to unlock it", http.StatusLocked)
+		case errors.As(err, &throttled):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+		default:
+			http.Error(w, "Failed to login", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Refresh godoc
+// @Summary Refresh an access token
+// @Description Exchange a refresh token for a new token pair. The presented refresh token is consumed.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param body body entity.RefreshRequest true "Refresh token"
+// @Success 200 {object} entity.TokenPair
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Invalid or expired refresh token"
+// @Failure 500 {string} string "Internal server error"
+// @Router /token/refresh [post]
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req entity.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.userUseCase.RefreshTokens(req.RefreshToken)
+	if err != nil {
+		switch err {
+		case usecase.ErrInvalidRefreshToken:
+			http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+			return
+		case usecase.ErrRefreshTokenReused:
+			http.Error(w, "Refresh token reuse detected, please log in again", http.StatusUnauthorized)
+			return
+		default:
+			http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Logout godoc
+// @Summary Logout the current session
+// @Description Revoke the access token used for this request and, if provided, the refresh token held by the client
+// @Tags auth
+// @Accept  json
+// @Security BearerAuth
+// @Param body body entity.LogoutRequest false "Refresh token to revoke"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /logout [post]
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req entity.LogoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
 			return
 		}
-		http.Error(w, "Failed to login", http.StatusInternalServerError)
+	}
+
+	if err := h.userUseCase.Logout(claims, req.RefreshToken); err != nil {
+		http.Error(w, "Failed to logout", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary Logout all sessions
+// @Description Invalidate every access and refresh token issued to the current user
+// @Tags auth
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /logout/all [post]
+func (h *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.userUseCase.LogoutAll(user.UserID); err != nil {
+		http.Error(w, "Failed to logout", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Change the current user's password. All sessions are ended and new tokens for this device are returned.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param body body entity.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} entity.TokenPair
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Current password is incorrect"
+// @Failure 423 {string} string "Account temporarily locked"
+// @Failure 429 {string} string "Too many failed attempts"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/password [post]
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req entity.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CurrentPassword == "" || req.NewPassword == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if !isValidPassword(req.NewPassword) {
+		http.Error(w, "Password does not meet strength requirements", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.userUseCase.ChangePassword(user, req.CurrentPassword, req.NewPassword, clientInfo(r))
+	if err != nil {
+		writePasswordConfirmationError(w, err, "Failed to change password")
 		return
 	}
 
-	// Return token
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"token": token,
-	})
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// DeactivateAccount godoc
+// @Summary Deactivate account
+// @Description Disable the current account and end all sessions. Sign in through /account/reactivate to undo.
+// @Tags account
+// @Accept  json
+// @Security BearerAuth
+// @Param body body entity.PasswordConfirmation true "Current password"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Password is incorrect"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/deactivate [post]
+func (h *UserHandler) DeactivateAccount(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req entity.PasswordConfirmation
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userUseCase.DeactivateAccount(user, req.Password, clientInfo(r)); err != nil {
+		writePasswordConfirmationError(w, err, "Failed to deactivate account")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteAccount godoc
+// @Summary Delete account
+// @Description Deactivate the current account and schedule it for deletion. After the grace period the account is erased; messages, reviews and exchanges other users take part in are kept without a link to it. Reactivating before then cancels the deletion.
+// @Tags account
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param body body entity.PasswordConfirmation true "Current password"
+// @Success 202 {object} entity.DeletionScheduled
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Password is incorrect"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me [delete]
+func (h *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req entity.PasswordConfirmation
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	deleteAt, err := h.userUseCase.ScheduleAccountDeletion(user, req.Password, clientInfo(r))
+	if err != nil {
+		writePasswordConfirmationError(w, err, "Failed to delete account")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(entity.DeletionScheduled{DeletionScheduledAt: deleteAt})
+}
+
+// ReactivateAccount godoc
+// @Summary Reactivate account
+// @Description Sign in to a deactivated account, re-enabling it and cancelling a scheduled deletion. Responds like /login.
+// @Tags account
+// @Accept  json
+// @Produce  json
+// @Param user body entity.UserCredentials true "User Credentials"
+// @Success 200 {object} entity.LoginResult
+// @Failure 400 {string} string "Invalid input"
+// @Failure 401 {string} string "Invalid username or password"
+// @Failure 403 {string} string "Account is suspended"
+// @Failure 409 {string} string "Account is not deactivated"
+// @Failure 423 {string} string "Account temporarily locked"
+// @Failure 429 {string} string "Too many failed login attempts"
+// @Failure 500 {string} string "Internal server error"
+// @Router /account/reactivate [post]
+func (h *UserHandler) ReactivateAccount(w http.ResponseWriter, r *http.Request) {
+	var creds entity.UserCredentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil || creds.Login() == "" || creds.Password == "" {
+		http.Error(w, "Username or email and password are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.userUseCase.ReactivateAccount(creds.Login(), creds.Password, clientInfo(r))
+	if err != nil {
+		var throttled *usecase.ThrottledError
+		switch {
+		case err == usecase.ErrInvalidCredentials:
+			http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		case err == usecase.ErrAccountDisabled:
+			http.Error(w, "Account is suspended", http.StatusForbidden)
+		case err == usecase.ErrAccountNotDeactivated:
+			http.Error(w, "Account is not deactivated, use /login", http.StatusConflict)
+		case err == usecase.ErrAccountLocked:
+			http.Error(w, "Account temporarily locked, check your email to unlock it", http.StatusLocked)
+		case errors.As(err, &throttled):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+		default:
+			http.Error(w, "Failed to reactivate account", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description List the devices the current user is logged in on. The session making the request is flagged as current.
+// @Tags auth
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} entity.Session
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/sessions [get]
+func (h *UserHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.userUseCase.ListSessions(claims.UserID, claims.SessionID)
+	if err != nil {
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Log out one of the current user's devices. Its tokens stop working immediately.
+// @Tags auth
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 204 "No Content"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Session not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /me/sessions/{id} [delete]
+func (h *UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.userUseCase.RevokeSession(user.UserID, sessionID); err != nil {
+		if err == usecase.ErrSessionNotFound {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writePasswordConfirmationError maps errors of actions that re-check the
+// current password
+func writePasswordConfirmationError(w http.ResponseWriter, err error, fallback string) {
+	var throttled *usecase.ThrottledError
+	switch {
+	case err == usecase.ErrWrongPassword:
+		http.Error(w, "Password is incorrect", http.StatusForbidden)
+	case err == usecase.ErrAccountLocked:
+		http.Error(w, "Account temporarily locked, check your email to unlock it", http.StatusLocked)
+	case errors.As(err, &throttled):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		http.Error(w, "Too many failed attempts, try again later", http.StatusTooManyRequests)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// clientInfo describes the caller of a request
+func clientInfo(r *http.Request) entity.ClientInfo {
+	userAgent := r.UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	return entity.ClientInfo{IP: middleware.ClientIP(r), UserAgent: userAgent}
 }
 
 // Utility functions for password validation
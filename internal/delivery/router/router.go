@@ -5,32 +5,119 @@ import (
 	"net/http"
 
 	"github.com/almatkai/book-exchange-backend/internal/delivery/router/handlers"
+	"github.com/almatkai/book-exchange-backend/internal/entity"
 	"github.com/almatkai/book-exchange-backend/internal/middleware"
 	"github.com/gorilla/mux"
 )
 
-func NewRouter(userHandler *handlers.UserHandler, jwtKey []byte) *mux.Router {
+func NewRouter(userHandler *handlers.UserHandler, accountHandler *handlers.AccountHandler, mfaHandler *handlers.MFAHandler, jwksHandler *handlers.JWKSHandler, adminHandler *handlers.AdminHandler, oidcHandler *handlers.OIDCHandler, apiKeyHandler *handlers.APIKeyHandler, privacyHandler *handlers.PrivacyHandler, profileHandler *handlers.ProfileHandler, followHandler *handlers.FollowHandler, blockHandler *handlers.BlockHandler, nearbyHandler *handlers.NearbyHandler, bookHandler *handlers.BookHandler, importHandler *handlers.ImportHandler, media http.Handler, auth *middleware.Authenticator, requireVerifiedEmail, trustProxy bool) *mux.Router {
 	router := mux.NewRouter()
+	router.Use(middleware.RealIP(trustProxy))
 
 	// Public routes
 	router.HandleFunc("/register", userHandler.Register).Methods(http.MethodPost)
 	router.HandleFunc("/login", userHandler.Login).Methods(http.MethodPost)
+	router.HandleFunc("/login/mfa", userHandler.LoginMFA).Methods(http.MethodPost)
+	router.HandleFunc("/token/refresh", userHandler.Refresh).Methods(http.MethodPost)
+	router.HandleFunc("/password/forgot", accountHandler.ForgotPassword).Methods(http.MethodPost)
+	router.HandleFunc("/password/reset", accountHandler.ResetPassword).Methods(http.MethodPost)
+	router.HandleFunc("/account/reactivate", userHandler.ReactivateAccount).Methods(http.MethodPost)
+	router.HandleFunc("/account/unlock", accountHandler.UnlockAccount).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/verify-email", accountHandler.VerifyEmail).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/auth/oidc/providers", oidcHandler.ListProviders).Methods(http.MethodGet)
+	router.HandleFunc("/auth/oidc/{provider}/login", oidcHandler.Login).Methods(http.MethodGet)
+	router.HandleFunc("/auth/oidc/{provider}/callback", oidcHandler.Callback).Methods(http.MethodGet)
+	router.HandleFunc("/users/{username}", profileHandler.GetUserProfile).Methods(http.MethodGet)
+	router.HandleFunc("/users/{username}/followers", followHandler.ListFollowers).Methods(http.MethodGet)
+	router.HandleFunc("/users/{username}/following", followHandler.ListFollowing).Methods(http.MethodGet)
+	if media != nil {
+		// Uploaded files, when they are kept on local disk
+		router.PathPrefix("/media/").Handler(http.StripPrefix("/media/", media)).Methods(http.MethodGet, http.MethodHead)
+	}
+	router.HandleFunc("/.well-known/jwks.json", jwksHandler.GetJWKS).Methods(http.MethodGet)
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Welcome to the Book Exchange API"))
 	}).Methods(http.MethodGet)
 
-	// Protected routes
-	protected := router.PathPrefix("/protected").Subrouter()
-	protected.Use(func(next http.Handler) http.Handler {
-		return middleware.AuthMiddleware(next, jwtKey)
-	})
-	protected.HandleFunc("/profile", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("This is protected route"))
-	}).Methods(http.MethodGet)
+	// Authenticated routes
+	authed := router.NewRoute().Subrouter()
+	authed.Use(auth.Middleware)
+	authed.HandleFunc("/verify-email/resend", accountHandler.ResendVerification).Methods(http.MethodPost)
+	authed.Handle("/me", guarded(profileHandler.GetMe, entity.PermProfileRead)).Methods(http.MethodGet)
+	authed.Handle("/me", guarded(profileHandler.UpdateMe, entity.PermProfileWrite)).Methods(http.MethodPatch)
+	authed.Handle("/me/avatar", guarded(profileHandler.UploadAvatar, entity.PermProfileWrite)).Methods(http.MethodPut)
+	authed.Handle("/me/avatar", guarded(profileHandler.DeleteAvatar, entity.PermProfileWrite)).Methods(http.MethodDelete)
+	authed.Handle("/me/location", guarded(profileHandler.SetLocation, entity.PermProfileWrite)).Methods(http.MethodPut)
+	authed.Handle("/me/location", guarded(profileHandler.ClearLocation, entity.PermProfileWrite)).Methods(http.MethodDelete)
+	authed.Handle("/users/{username}/follow", guarded(followHandler.Follow, entity.PermProfileWrite)).Methods(http.MethodPut)
+	authed.Handle("/users/{username}/follow", guarded(followHandler.Unfollow, entity.PermProfileWrite)).Methods(http.MethodDelete)
+	authed.Handle("/users/{username}/block", guarded(blockHandler.Block, entity.PermProfileWrite)).Methods(http.MethodPut)
+	authed.Handle("/users/{username}/block", guarded(blockHandler.Unblock, entity.PermProfileWrite)).Methods(http.MethodDelete)
+	authed.Handle("/me/blocks", guarded(blockHandler.ListBlocked, entity.PermProfileRead)).Methods(http.MethodGet)
+	authed.Handle("/feed", guarded(followHandler.Feed, entity.PermProfileRead)).Methods(http.MethodGet)
+	authed.Handle("/me/books", guarded(bookHandler.ListMyBooks, entity.PermBooksRead)).Methods(http.MethodGet)
+	authed.Handle("/users/{username}/books", guarded(bookHandler.ListUserBooks, entity.PermBooksRead)).Methods(http.MethodGet)
+	authed.Handle("/books", guarded(bookHandler.FindBooksByISBN, entity.PermBooksRead)).Methods(http.MethodGet)
+	authed.Handle("/books/metadata", guarded(bookHandler.LookupMetadata, entity.PermBooksRead)).Methods(http.MethodGet)
+	authed.Handle("/books/{id:[0-9]+}", guarded(bookHandler.GetBook, entity.PermBooksRead)).Methods(http.MethodGet)
+	authed.Handle("/books/{id:[0-9]+}/condition", guarded(bookHandler.ConditionHistory, entity.PermBooksRead)).Methods(http.MethodGet)
+	authed.Handle("/me/imports", guarded(importHandler.ListImports, entity.PermBooksRead)).Methods(http.MethodGet)
+	authed.Handle("/me/imports/{id}", guarded(importHandler.GetImport, entity.PermBooksRead)).Methods(http.MethodGet)
+	authed.Handle("/nearby/books", guarded(nearbyHandler.NearbyBooks, entity.PermBooksRead)).Methods(http.MethodGet)
+	authed.Handle("/nearby/posts", guarded(nearbyHandler.NearbyPosts, entity.PermBooksRead)).Methods(http.MethodGet)
+
+	// Account security; not available to API keys
+	session := authed.NewRoute().Subrouter()
+	session.Use(middleware.RequireSession)
+	session.HandleFunc("/logout", userHandler.Logout).Methods(http.MethodPost)
+	session.HandleFunc("/logout/all", userHandler.LogoutAll).Methods(http.MethodPost)
+	session.HandleFunc("/me/password", userHandler.ChangePassword).Methods(http.MethodPost)
+	session.HandleFunc("/me", userHandler.DeleteAccount).Methods(http.MethodDelete)
+	session.HandleFunc("/me/deactivate", userHandler.DeactivateAccount).Methods(http.MethodPost)
+	session.HandleFunc("/me/export", privacyHandler.ExportData).Methods(http.MethodGet)
+	session.HandleFunc("/me/sessions", userHandler.ListSessions).Methods(http.MethodGet)
+	session.HandleFunc("/me/sessions/{id}", userHandler.RevokeSession).Methods(http.MethodDelete)
+	session.HandleFunc("/me/2fa/totp/enroll", mfaHandler.EnrollTOTP).Methods(http.MethodPost)
+	session.HandleFunc("/me/2fa/totp/confirm", mfaHandler.ConfirmTOTP).Methods(http.MethodPost)
+	session.HandleFunc("/me/2fa/totp/disable", mfaHandler.DisableTOTP).Methods(http.MethodPost)
+	session.HandleFunc("/me/2fa/recovery-codes", mfaHandler.RegenerateRecoveryCodes).Methods(http.MethodPost)
+	session.HandleFunc("/me/identities", oidcHandler.ListIdentities).Methods(http.MethodGet)
+	session.HandleFunc("/me/identities/{provider}", oidcHandler.LinkIdentity).Methods(http.MethodPost)
+	session.HandleFunc("/me/api-keys", apiKeyHandler.CreateAPIKey).Methods(http.MethodPost)
+	session.HandleFunc("/me/api-keys", apiKeyHandler.ListAPIKeys).Methods(http.MethodGet)
+	session.HandleFunc("/me/api-keys/{id}", apiKeyHandler.RevokeAPIKey).Methods(http.MethodDelete)
+
+	// Administration and moderation
+	admin := authed.PathPrefix("/admin").Subrouter()
+	admin.Handle("/users", guarded(adminHandler.ListUsers, entity.PermUsersRead)).Methods(http.MethodGet)
+	admin.Handle("/roles", guarded(adminHandler.ListRoles, entity.PermUsersRead)).Methods(http.MethodGet)
+	admin.Handle("/users/{id:[0-9]+}/roles", guarded(adminHandler.SetUserRoles, entity.PermUsersManageRoles)).Methods(http.MethodPut)
+	admin.Handle("/audit-logs", guarded(adminHandler.ListAuditLogs, entity.PermAuditRead)).Methods(http.MethodGet)
+
+	moderation := authed.PathPrefix("/moderation").Subrouter()
+	moderation.Handle("/users/{id:[0-9]+}/suspend", guarded(adminHandler.SuspendUser, entity.PermUsersSuspend)).Methods(http.MethodPost)
+	moderation.Handle("/users/{id:[0-9]+}/unsuspend", guarded(adminHandler.UnsuspendUser, entity.PermUsersSuspend)).Methods(http.MethodPost)
+
+	// Exchange features; when configured, only users with a verified email may use them
+	exchange := authed.NewRoute().Subrouter()
+	if requireVerifiedEmail {
+		exchange.Use(middleware.RequireVerifiedEmail)
+	}
+	exchange.Handle("/books", guarded(bookHandler.CreateBook, entity.PermBooksWrite)).Methods(http.MethodPost)
+	exchange.Handle("/books/{id:[0-9]+}", guarded(bookHandler.UpdateBook, entity.PermBooksWrite)).Methods(http.MethodPatch)
+	exchange.Handle("/books/{id:[0-9]+}", guarded(bookHandler.DeleteBook, entity.PermBooksWrite)).Methods(http.MethodDelete)
+	exchange.Handle("/books/{id:[0-9]+}/availability", guarded(bookHandler.SetAvailability, entity.PermBooksWrite)).Methods(http.MethodPut)
+	exchange.Handle("/books/{id:[0-9]+}/condition", guarded(bookHandler.RecordCondition, entity.PermBooksWrite)).Methods(http.MethodPost)
+	exchange.Handle("/me/imports", guarded(importHandler.StartImport, entity.PermBooksWrite)).Methods(http.MethodPost)
 
 	return router
 }
 
+// guarded wraps a handler so it is only reachable with all of the given permissions
+func guarded(h http.HandlerFunc, perms ...string) http.Handler {
+	return middleware.RequirePermission(perms...)(h)
+}
+
 //curl -X POST http://localhost:8000/register -H "Content-Type: application/json" -d "{\"username\": \"almatKAI\", \"email\": \"almatkai@example.com\", \"password\": \"password123\"}"
 //
 //curl -X POST http://localhost:8000/register -H "Content-Type: application/json" -d "{\"username\": \"almatKAI\", \"email\": \"almatkai@example.com\", \"password\": \"password123\"}"
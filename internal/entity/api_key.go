@@ -0,0 +1,71 @@
+// internal/entity/api_key.go
+package entity
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix marks personal API keys so they are recognisable in logs and
+// secret scanners
+const APIKeyPrefix = "bx_"
+
+// APIKey is a long-lived credential for scripts. Only a hash of the key is
+// stored; Prefix keeps enough of it for users to tell their keys apart.
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID     int        `gorm:"not null;index" json:"-"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`
+	KeyHash    string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Scopes     ScopeList  `gorm:"type:text;not null" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+// Usable reports whether the key may authenticate requests at the given time
+func (k *APIKey) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// ScopeList is a set of permission names stored as a space separated string
+type ScopeList []string
+
+// Value implements driver.Valuer
+func (s ScopeList) Value() (driver.Value, error) {
+	return strings.Join(s, " "), nil
+}
+
+// Scan implements sql.Scanner
+func (s *ScopeList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		*s = strings.Fields(v)
+	case []byte:
+		*s = strings.Fields(string(v))
+	case nil:
+		*s = nil
+	default:
+		return fmt.Errorf("cannot scan %T into ScopeList", value)
+	}
+	return nil
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresInDays is optional; keys without it never expire
+	ExpiresInDays int `json:"expires_in_days,omitempty"`
+}
+
+// CreatedAPIKey carries the plaintext key, which is only ever shown once
+type CreatedAPIKey struct {
+	Key string `json:"key"`
+	APIKey
+}
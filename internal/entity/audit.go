@@ -0,0 +1,38 @@
+// internal/entity/audit.go
+package entity
+
+import (
+	"time"
+)
+
+// Audit log actions
+const (
+	AuditAccountLocked            = "account_locked"
+	AuditAccountUnlocked          = "account_unlocked"
+	AuditIPLocked                 = "ip_locked"
+	AuditRolesChanged             = "roles_changed"
+	AuditUserSuspended            = "user_suspended"
+	AuditUserUnsuspended          = "user_unsuspended"
+	AuditAPIKeyCreated            = "api_key_created"
+	AuditAPIKeyRevoked            = "api_key_revoked"
+	AuditAccountDeactivated       = "account_deactivated"
+	AuditAccountReactivated       = "account_reactivated"
+	AuditAccountDeletionScheduled = "account_deletion_scheduled"
+	AuditAccountDeleted           = "account_deleted"
+)
+
+// AuditLog is an append-only record of a security relevant event
+type AuditLog struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	UserID    *int      `gorm:"index" json:"user_id,omitempty"`
+	Action    string    `gorm:"size:50;not null" json:"action"`
+	IP        string    `gorm:"column:ip_address;size:45" json:"ip_address,omitempty"`
+	Details   string    `gorm:"type:text" json:"details,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// ClientInfo describes where a request came from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
@@ -0,0 +1,14 @@
+// internal/entity/block.go
+package entity
+
+import (
+	"time"
+)
+
+// UserBlock records that BlockerID blocked BlockedID. It applies both ways:
+// the two users cannot interact and do not see each other's content.
+type UserBlock struct {
+	BlockerID int       `gorm:"primaryKey" json:"blocker_id"`
+	BlockedID int       `gorm:"primaryKey" json:"blocked_id"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -3,21 +3,99 @@ package entity
 
 import (
 	"time"
+)
 
-	"github.com/google/uuid"
+// Book conditions, as in the book_condition enum
+const (
+	ConditionNew      = "New"
+	ConditionLikeNew  = "Like New"
+	ConditionVeryGood = "Very Good"
+	ConditionGood     = "Good"
+	ConditionFair     = "Fair"
+	ConditionPoor     = "Poor"
 )
 
+// BookConditions lists the conditions from best to worst
+var BookConditions = []string{ConditionNew, ConditionLikeNew, ConditionVeryGood, ConditionGood, ConditionFair, ConditionPoor}
+
 type Book struct {
-	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	UserID      uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
-	Title       string    `gorm:"type:varchar(255);not null" json:"title"`
-	Author      string    `gorm:"type:varchar(255);not null" json:"author"`
+	BookID      int       `gorm:"primaryKey;column:book_id" json:"id"`
+	OwnerID     int       `gorm:"not null" json:"owner_id"`
+	Title       string    `gorm:"size:255;not null" json:"title"`
+	Author      string    `gorm:"size:255;not null" json:"author"`
+	ISBN        *string   `gorm:"column:isbn;size:13" json:"isbn,omitempty"`
+	Condition   string    `gorm:"type:book_condition;not null" json:"condition"`
 	Description string    `gorm:"type:text" json:"description,omitempty"`
 	IsAvailable bool      `gorm:"default:true" json:"is_available"`
 	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 
-	// Relationships
-	User  User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Posts []Post `gorm:"foreignKey:BookID" json:"posts,omitempty"`
+	Genre    string `gorm:"size:50" json:"genre,omitempty"`
+	Language string `gorm:"size:50" json:"language,omitempty"`
+	// PublicationYear is nil when unknown
+	PublicationYear *int   `json:"publication_year,omitempty"`
+	ImageURL        string `gorm:"column:image_url;size:255" json:"image_url,omitempty"`
+
+	// Latitude and Longitude are the owner's approximate position
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+
+	// Owner is the owner's username; it is only loaded, never written
+	Owner string `gorm:"->" json:"owner,omitempty"`
+}
+
+// CreateBookRequest lists a new book. ISBN may be an ISBN-10 or ISBN-13,
+// with or without hyphens. A PublicationYear of 0 means unknown.
+type CreateBookRequest struct {
+	Title           string `json:"title"`
+	Author          string `json:"author"`
+	ISBN            string `json:"isbn,omitempty"`
+	Condition       string `json:"condition"`
+	Description     string `json:"description,omitempty"`
+	Genre           string `json:"genre,omitempty"`
+	Language        string `json:"language,omitempty"`
+	PublicationYear int    `json:"publication_year,omitempty"`
+	ImageURL        string `json:"image_url,omitempty"`
+}
+
+// UpdateBookRequest is a partial book update; omitted fields are left
+// unchanged. An empty ISBN or a PublicationYear of 0 removes it.
+type UpdateBookRequest struct {
+	Title           *string `json:"title,omitempty"`
+	Author          *string `json:"author,omitempty"`
+	ISBN            *string `json:"isbn,omitempty"`
+	Condition       *string `json:"condition,omitempty"`
+	Description     *string `json:"description,omitempty"`
+	Genre           *string `json:"genre,omitempty"`
+	Language        *string `json:"language,omitempty"`
+	PublicationYear *int    `json:"publication_year,omitempty"`
+	ImageURL        *string `json:"image_url,omitempty"`
+}
+
+// BookConditionChange is an entry in a book's condition history. One is
+// recorded when the book is listed and whenever its condition changes.
+type BookConditionChange struct {
+	ChangeID  int       `gorm:"primaryKey;column:change_id" json:"id"`
+	BookID    int       `gorm:"not null" json:"book_id"`
+	Condition string    `gorm:"type:book_condition;not null" json:"condition"`
+	Note      string    `gorm:"type:text" json:"note,omitempty"`
+	ChangedAt time.Time `gorm:"autoCreateTime" json:"changed_at"`
+}
+
+// RecordConditionRequest sets a book's condition, with an optional note
+// such as "coffee stain on the cover"
+type RecordConditionRequest struct {
+	Condition string `json:"condition"`
+	Note      string `json:"note,omitempty"`
+}
+
+// BookMetadata is what an external catalog knows about an edition
+type BookMetadata struct {
+	ISBN            string   `json:"isbn"`
+	Title           string   `json:"title"`
+	Authors         []string `json:"authors,omitempty"`
+	CoverURL        string   `json:"cover_url,omitempty"`
+	PublicationYear int      `json:"publication_year,omitempty"`
+	Language        string   `json:"language,omitempty"`
+	PageCount       int      `json:"page_count,omitempty"`
 }
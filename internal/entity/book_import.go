@@ -0,0 +1,78 @@
+// internal/entity/book_import.go
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Import file formats
+const (
+	ImportFormatCSV       = "csv"
+	ImportFormatGoodreads = "goodreads"
+)
+
+// Import job statuses
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// ImportJob tracks a bulk import of books from a file
+type ImportJob struct {
+	ID            uuid.UUID    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID        int          `gorm:"not null" json:"-"`
+	Format        string       `gorm:"size:20;not null" json:"format"`
+	Status        string       `gorm:"size:20;not null" json:"status"`
+	TotalRows     int          `gorm:"not null" json:"total_rows"`
+	ProcessedRows int          `gorm:"not null" json:"processed_rows"`
+	Imported      int          `gorm:"not null" json:"imported"`
+	Skipped       int          `gorm:"not null" json:"skipped"`
+	Failed        int          `gorm:"not null" json:"failed"`
+	Errors        ImportErrors `gorm:"type:jsonb" json:"errors,omitempty"`
+	CreatedAt     time.Time    `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time    `gorm:"autoUpdateTime" json:"updated_at"`
+	FinishedAt    *time.Time   `json:"finished_at,omitempty"`
+}
+
+// ImportRowError explains why a row was not imported. Row is the line in the
+// file, the header being line 1; Skipped rows were left out on purpose, such
+// as duplicates.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+	Skipped bool   `json:"skipped,omitempty"`
+}
+
+// ImportErrors is stored as a JSON array
+type ImportErrors []ImportRowError
+
+// Value implements driver.Valuer
+func (e ImportErrors) Value() (driver.Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(e)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner
+func (e *ImportErrors) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	case []byte:
+		return json.Unmarshal(v, e)
+	case nil:
+		*e = nil
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into ImportErrors", value)
+	}
+}
@@ -19,6 +19,11 @@ type Exchange struct {
 	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 
+	// MeetingLatitude and MeetingLongitude pinpoint where the parties meet.
+	// Only the two of them see it, so it is not fuzzed.
+	MeetingLatitude  *float64 `json:"meeting_latitude,omitempty"`
+	MeetingLongitude *float64 `json:"meeting_longitude,omitempty"`
+
 	// Relationships
 	Post      Post      `gorm:"foreignKey:PostID" json:"post,omitempty"`
 	Requester User      `gorm:"foreignKey:RequesterID" json:"requester,omitempty"`
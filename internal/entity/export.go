@@ -0,0 +1,19 @@
+// internal/entity/export.go
+package entity
+
+import (
+	"time"
+)
+
+// DataExport is everything stored about a user. Data holds one list of rows
+// per section, e.g. "books" or "messages".
+type DataExport struct {
+	GeneratedAt time.Time                           `json:"generated_at"`
+	Profile     *User                               `json:"profile"`
+	Data        map[string][]map[string]interface{} `json:"data"`
+}
+
+// DeletionScheduled confirms an account deletion request
+type DeletionScheduled struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
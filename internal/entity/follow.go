@@ -0,0 +1,49 @@
+// internal/entity/follow.go
+package entity
+
+import (
+	"time"
+)
+
+// Follow records that FollowerID follows FolloweeID
+type Follow struct {
+	FollowerID int       `gorm:"primaryKey" json:"follower_id"`
+	FolloweeID int       `gorm:"primaryKey" json:"followee_id"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Feed item types
+const (
+	FeedListing  = "listing"  // a followed user listed a book
+	FeedReview   = "review"   // a followed user reviewed someone
+	FeedExchange = "exchange" // a followed user completed an exchange
+)
+
+// FeedItem is one activity in the feed. Actor is the followed user; Subject
+// is the other user involved in a review or exchange.
+type FeedItem struct {
+	Type       string    `json:"type"`
+	ID         int       `json:"id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Actor      string    `json:"actor"`
+	Subject    string    `json:"subject,omitempty"`
+	BookID     *int      `json:"book_id,omitempty"`
+	BookTitle  string    `json:"book_title,omitempty"`
+	BookAuthor string    `json:"book_author,omitempty"`
+	Rating     *int      `json:"rating,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
+}
+
+// FeedCursor is the position of the last item of a feed page. Items are
+// ordered by OccurredAt, Type and ID, newest first.
+type FeedCursor struct {
+	OccurredAt time.Time `json:"t"`
+	Type       string    `json:"k"`
+	ID         int       `json:"id"`
+}
+
+// FeedPage is a page of the feed; NextCursor is empty on the last page
+type FeedPage struct {
+	Items      []FeedItem `json:"items"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
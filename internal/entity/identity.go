@@ -0,0 +1,46 @@
+// internal/entity/identity.go
+package entity
+
+import (
+	"time"
+)
+
+// ExternalIdentity links a user to an account at an OpenID Connect provider.
+// A provider/subject pair belongs to at most one user.
+type ExternalIdentity struct {
+	ID          int        `gorm:"primaryKey" json:"id"`
+	UserID      int        `gorm:"not null;index" json:"-"`
+	Provider    string     `gorm:"size:50;not null" json:"provider"`
+	Subject     string     `gorm:"size:255;not null" json:"-"`
+	Email       string     `gorm:"size:255" json:"email,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// ExternalProfile is what an identity provider asserted about a user
+type ExternalProfile struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	FullName      string
+}
+
+// OIDCLoginState remembers an authorization request until the provider
+// redirects back. Only a hash of the state parameter is stored. UserID is set
+// when a signed-in user links a provider instead of logging in.
+type OIDCLoginState struct {
+	StateHash    string `gorm:"primaryKey;size:64"`
+	Provider     string `gorm:"size:50;not null"`
+	CodeVerifier string `gorm:"size:128;not null"`
+	Nonce        string `gorm:"size:64;not null"`
+	UserID       *int
+	ExpiresAt    time.Time `gorm:"not null"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+// OIDCRedirect is returned when the client has to navigate to a provider itself
+type OIDCRedirect struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
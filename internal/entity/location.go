@@ -0,0 +1,56 @@
+// internal/entity/location.go
+package entity
+
+// Coordinates is a WGS 84 position in decimal degrees
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// BoundingBox limits a search to a rectangle. MinLongitude is greater than
+// MaxLongitude when the box crosses the antimeridian.
+type BoundingBox struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+// NearbyQuery searches around Center, within RadiusKm or, when Bounds is
+// set, inside Bounds. Results are sorted by distance from Center. A nil
+// Center means the searching user's own location.
+type NearbyQuery struct {
+	Center   *Coordinates
+	RadiusKm float64
+	Bounds   *BoundingBox
+	Limit    int
+	Offset   int
+}
+
+// NearbyBook is an available book found by a location search. The position
+// is the owner's approximate location, never their exact address.
+type NearbyBook struct {
+	BookID     int     `json:"book_id"`
+	Title      string  `json:"title"`
+	Author     string  `json:"author"`
+	Condition  string  `json:"condition"`
+	ImageURL   string  `json:"image_url,omitempty"`
+	Owner      string  `json:"owner"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// NearbyPost is a published post found by a location search, positioned at
+// its author's approximate location
+type NearbyPost struct {
+	PostID     int     `json:"post_id"`
+	Type       string  `json:"type"`
+	Title      string  `json:"title"`
+	Summary    string  `json:"summary,omitempty"`
+	Author     string  `json:"author"`
+	BookID     *int    `json:"book_id,omitempty"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	DistanceKm float64 `json:"distance_km"`
+}
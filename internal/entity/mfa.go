@@ -0,0 +1,51 @@
+// internal/entity/mfa.go
+package entity
+
+import (
+	"time"
+)
+
+// RecoveryCode is a hashed single-use code that can replace a TOTP code
+type RecoveryCode struct {
+	ID        int        `gorm:"primaryKey" json:"-"`
+	UserID    int        `gorm:"not null;index" json:"-"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"-"`
+	UsedAt    *time.Time `json:"-"`
+}
+
+// TOTPEnrollment is returned when a user starts setting up an authenticator app
+type TOTPEnrollment struct {
+	Secret    string `json:"secret"`
+	URI       string `json:"otpauth_uri"`
+	QRCodePNG []byte `json:"qr_code_png"` // base64 encoded in JSON
+}
+
+// LoginResult is either a token pair or, for accounts with two-factor
+// authentication, a short-lived token to complete the second login step
+type LoginResult struct {
+	*TokenPair
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	// Code is either a current TOTP code or an unused recovery code
+	Code string `json:"code"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+// MFAChangeRequest re-authenticates with the password and a TOTP or recovery
+// code before two-factor settings change
+type MFAChangeRequest struct {
+	PasswordConfirmation
+	Code string `json:"code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
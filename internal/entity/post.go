@@ -17,6 +17,10 @@ type Post struct {
 	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 
+	// Latitude and Longitude are the author's fuzzed position, used by nearby search
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+
 	// Relationships
 	User      User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Book      Book       `gorm:"foreignKey:BookID" json:"book,omitempty"`
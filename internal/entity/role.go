@@ -0,0 +1,68 @@
+// internal/entity/role.go
+package entity
+
+import (
+	"time"
+)
+
+// Built-in roles
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Permissions checked by route-level authorization
+const (
+	PermProfileRead      = "profile:read"
+	PermProfileWrite     = "profile:write"
+	PermBooksRead        = "books:read"
+	PermBooksWrite       = "books:write"
+	PermUsersRead        = "users:read"
+	PermUsersSuspend     = "users:suspend"
+	PermUsersManageRoles = "users:manage_roles"
+	PermAuditRead        = "audit:read"
+)
+
+type Permission struct {
+	ID          int    `gorm:"primaryKey;column:permission_id" json:"-"`
+	Name        string `gorm:"size:100;unique;not null" json:"name"`
+	Description string `gorm:"type:text" json:"description,omitempty"`
+}
+
+type Role struct {
+	ID          int          `gorm:"primaryKey;column:role_id" json:"-"`
+	Name        string       `gorm:"size:50;unique;not null" json:"name"`
+	Description string       `gorm:"type:text" json:"description,omitempty"`
+	Permissions []Permission `gorm:"many2many:role_permissions;joinForeignKey:RoleID;joinReferences:PermissionID" json:"permissions"`
+}
+
+// UserRole assigns a role to a user
+type UserRole struct {
+	UserID    int       `gorm:"primaryKey" json:"user_id"`
+	RoleID    int       `gorm:"primaryKey" json:"role_id"`
+	GrantedBy *int      `json:"granted_by,omitempty"`
+	GrantedAt time.Time `gorm:"autoCreateTime" json:"granted_at"`
+}
+
+// UserWithRoles is the administrative view of an account
+type UserWithRoles struct {
+	User
+	Roles []string `json:"roles"`
+}
+
+type SetRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+type SuspendRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Page is a window of a larger result set
+type Page[T any] struct {
+	Items  []T   `json:"items"`
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+}
@@ -0,0 +1,22 @@
+// internal/entity/session.go
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a login on one device. Its ID is the family ID of the refresh
+// tokens issued for the login and is carried in access tokens as "sid".
+type Session struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     int        `gorm:"not null;index" json:"-"`
+	UserAgent  string     `gorm:"size:512" json:"user_agent,omitempty"`
+	IP         string     `gorm:"column:ip_address;size:45" json:"ip_address,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"-"`
+	// Current marks the session the request was made with
+	Current bool `gorm:"-" json:"current"`
+}
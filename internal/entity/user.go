@@ -2,6 +2,10 @@
 package entity
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -17,6 +21,63 @@ type User struct {
 	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
 	LastLogin *time.Time `json:"last_login,omitempty"`
 	IsActive  bool       `gorm:"default:true" json:"is_active"`
+
+	// EmailVerifiedAt is nil until the user confirms their email address
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+
+	// TOTP two-factor authentication. The secret is set during enrollment and
+	// only enforced once TOTPEnabledAt is set; TOTPLastStep blocks code replay.
+	TOTPSecret    string     `gorm:"column:totp_secret;size:64" json:"-"`
+	TOTPEnabledAt *time.Time `gorm:"column:totp_enabled_at" json:"-"`
+	TOTPLastStep  int64      `gorm:"column:totp_last_step;not null;default:0" json:"-"`
+
+	// Failed login tracking; LockedUntil is set once too many attempts failed
+	FailedLoginCount  int        `gorm:"not null;default:0" json:"-"`
+	LastFailedLoginAt *time.Time `json:"-"`
+	LockedUntil       *time.Time `json:"-"`
+
+	// DeactivatedAt is set when the owner deactivated the account, as opposed
+	// to a suspension. DeletionScheduledAt is when the account will be erased.
+	DeactivatedAt       *time.Time `json:"deactivated_at,omitempty"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	// SuspendedAt is set while a moderator has suspended the account
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+
+	// TokenVersion is embedded in access tokens; bumping it invalidates all of them
+	TokenVersion int `gorm:"not null;default:0" json:"-"`
+
+	// AvatarID names the current set of avatar thumbnails in storage;
+	// AvatarURLs maps each thumbnail size in pixels to its public URL
+	AvatarID   *string    `gorm:"size:36" json:"-"`
+	AvatarURLs AvatarURLs `gorm:"type:jsonb" json:"avatar_urls,omitempty"`
+
+	// Latitude and Longitude are the exact position the user set. Only the
+	// user sees them; listings carry a fuzzed copy.
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+}
+
+// Coordinates returns the user's exact position, or nil when it is not set
+func (u *User) Coordinates() *Coordinates {
+	if u.Latitude == nil || u.Longitude == nil {
+		return nil
+	}
+	return &Coordinates{Latitude: *u.Latitude, Longitude: *u.Longitude}
+}
+
+// TOTPEnabled reports whether logins require a second factor
+func (u *User) TOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// IsLocked reports whether the account is temporarily locked at the given time
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// EmailVerified reports whether the user has confirmed their email address
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 type UserCredentials struct {
@@ -24,3 +85,88 @@ type UserCredentials struct {
 	Password string `json:"password"`
 	Email    string `json:"email,omitempty"`
 }
+
+// Login returns the identifier to log in with: the username, or the email
+// address when no username was given. Either may hold an email address.
+func (c UserCredentials) Login() string {
+	if login := strings.TrimSpace(c.Username); login != "" {
+		return login
+	}
+	return strings.TrimSpace(c.Email)
+}
+
+// SelfDeactivated reports whether the owner deactivated the account and may
+// reactivate it, which is not the case while it is suspended
+func (u *User) SelfDeactivated() bool {
+	return !u.IsActive && u.DeactivatedAt != nil && u.SuspendedAt == nil
+}
+
+// PasswordConfirmation re-authenticates a signed-in user for a sensitive action
+type PasswordConfirmation struct {
+	Password string `json:"password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// PublicProfile is what other users can see of an account
+type PublicProfile struct {
+	Username   string     `json:"username"`
+	FullName   string     `json:"full_name,omitempty"`
+	Location   string     `json:"location,omitempty"`
+	Bio        string     `json:"bio,omitempty"`
+	Rating     float32    `json:"rating"`
+	AvatarURLs AvatarURLs `json:"avatar_urls,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// PublicProfile returns the public view of the user, without the email
+// address and account details
+func (u *User) PublicProfile() *PublicProfile {
+	return &PublicProfile{
+		Username:   u.Username,
+		FullName:   u.FullName,
+		Location:   u.Location,
+		Bio:        u.Bio,
+		Rating:     u.Rating,
+		AvatarURLs: u.AvatarURLs,
+		CreatedAt:  u.CreatedAt,
+	}
+}
+
+// UpdateProfileRequest is a partial profile update. Omitted fields are left
+// unchanged; an empty string clears a field.
+type UpdateProfileRequest struct {
+	FullName *string `json:"full_name,omitempty"`
+	Location *string `json:"location,omitempty"`
+	Bio      *string `json:"bio,omitempty"`
+}
+
+// AvatarURLs maps avatar sizes in pixels, such as "128", to image URLs
+type AvatarURLs map[string]string
+
+// Value implements driver.Valuer
+func (a AvatarURLs) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(a)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner
+func (a *AvatarURLs) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	case []byte:
+		return json.Unmarshal(v, a)
+	case nil:
+		*a = nil
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into AvatarURLs", value)
+	}
+}
@@ -1,14 +1,71 @@
 package middleware
 
 import (
-	"fmt"
-	"github.com/golang-jwt/jwt"
+	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+	"github.com/almatkai/book-exchange-backend/pkg/utils"
+)
+
+type contextKey string
+
+const (
+	userContextKey        contextKey = "user"
+	claimsContextKey      contextKey = "claims"
+	permissionsContextKey contextKey = "permissions"
+	apiKeyContextKey      contextKey = "api_key"
 )
 
-// Middleware function to verify JWT
-func AuthMiddleware(next http.Handler, jwtKey []byte) http.Handler {
+// Authenticator verifies bearer tokens and API keys and loads the user they
+// were issued to
+type Authenticator struct {
+	jwtSvc      utils.JWTService
+	userRepo    repository.UserRepository
+	revokedRepo repository.RevokedTokenRepository
+	apiKeyRepo  repository.APIKeyRepository
+	roleRepo    repository.RoleRepository
+	sessionRepo repository.SessionRepository
+}
+
+// NewAuthenticator creates a new Authenticator
+func NewAuthenticator(jwtSvc utils.JWTService, userRepo repository.UserRepository, revokedRepo repository.RevokedTokenRepository, apiKeyRepo repository.APIKeyRepository, roleRepo repository.RoleRepository, sessionRepo repository.SessionRepository) *Authenticator {
+	return &Authenticator{
+		jwtSvc:      jwtSvc,
+		userRepo:    userRepo,
+		revokedRepo: revokedRepo,
+		apiKeyRepo:  apiKeyRepo,
+		roleRepo:    roleRepo,
+		sessionRepo: sessionRepo,
+	}
+}
+
+// authError is an authentication failure and the response it maps to
+type authError struct {
+	status  int
+	message string
+}
+
+var (
+	errInvalidToken   = &authError{http.StatusUnauthorized, "Invalid token"}
+	errTokenRevoked   = &authError{http.StatusUnauthorized, "Token has been revoked"}
+	errSessionRevoked = &authError{http.StatusUnauthorized, "Session has been revoked"}
+	errInactive       = &authError{http.StatusUnauthorized, "Account is inactive"}
+	errInvalidKey     = &authError{http.StatusUnauthorized, "Invalid API key"}
+	errAuthFailed     = &authError{http.StatusInternalServerError, "Failed to authenticate"}
+)
+
+// Middleware authenticates the request with a bearer access token or a
+// personal API key ("Authorization: ApiKey <key>") and attaches the calling
+// user and their permissions to the request context
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -17,28 +74,237 @@ func AuthMiddleware(next http.Handler, jwtKey []byte) http.Handler {
 		}
 
 		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		if len(parts) != 2 {
+			http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
+			return
+		}
+
+		var ctx context.Context
+		var authErr *authError
+		switch parts[0] {
+		case "Bearer":
+			ctx, authErr = a.authenticateToken(r.Context(), parts[1], ClientIP(r))
+		case "ApiKey":
+			ctx, authErr = a.authenticateAPIKey(r.Context(), parts[1])
+		default:
 			http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
 			return
 		}
+		if authErr != nil {
+			http.Error(w, authErr.message, authErr.status)
+			return
+		}
+
+		// Credentials are valid; proceed to the next handler
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (a *Authenticator) authenticateToken(ctx context.Context, token, ip string) (context.Context, *authError) {
+	// Parse and verify the token
+	claims, err := a.jwtSvc.ValidateToken(token)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	revoked, err := a.revokedRepo.IsRevoked(claims.ID)
+	if err != nil {
+		return nil, errAuthFailed
+	}
+	if revoked {
+		return nil, errTokenRevoked
+	}
+
+	user, authErr := a.activeUser(claims.UserID, errInvalidToken)
+	if authErr != nil {
+		return nil, authErr
+	}
+	if claims.TokenVersion != user.TokenVersion {
+		return nil, errTokenRevoked
+	}
+	if authErr := a.checkSession(claims, ip); authErr != nil {
+		return nil, authErr
+	}
+
+	ctx = WithUser(ctx, user)
+	ctx = context.WithValue(ctx, claimsContextKey, claims)
+	return WithPermissions(ctx, claims.Permissions), nil
+}
+
+// checkSession rejects tokens of a revoked session and records activity on
+// live ones. Tokens issued before sessions were tracked carry no sid.
+func (a *Authenticator) checkSession(claims *utils.Claims, ip string) *authError {
+	if claims.SessionID == "" {
+		return nil
+	}
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return errInvalidToken
+	}
+
+	session, err := a.sessionRepo.FindByID(sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return errSessionRevoked
+		}
+		return errAuthFailed
+	}
+	if session.RevokedAt != nil || session.UserID != claims.UserID {
+		return errSessionRevoked
+	}
+
+	if err := a.sessionRepo.TouchLastSeen(session.ID, ip, time.Now()); err != nil {
+		log.Printf("failed to record activity on session %s: %v", session.ID, err)
+	}
+	return nil
+}
+
+// authenticateAPIKey grants the key's scopes, limited to the permissions the
+// owner still holds so that losing a role also narrows existing keys
+func (a *Authenticator) authenticateAPIKey(ctx context.Context, rawKey string) (context.Context, *authError) {
+	if !strings.HasPrefix(rawKey, entity.APIKeyPrefix) {
+		return nil, errInvalidKey
+	}
+	key, err := a.apiKeyRepo.FindByHash(utils.HashToken(rawKey))
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, errInvalidKey
+		}
+		return nil, errAuthFailed
+	}
+	now := time.Now()
+	if !key.Usable(now) {
+		return nil, errInvalidKey
+	}
 
-		tokenStr := parts[1]
+	user, authErr := a.activeUser(key.UserID, errInvalidKey)
+	if authErr != nil {
+		return nil, authErr
+	}
 
-		// Parse and verify the token
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			// Validate the algorithm
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method")
+	held, err := a.roleRepo.PermissionsForUser(user.UserID)
+	if err != nil {
+		return nil, errAuthFailed
+	}
+	var granted []string
+	for _, scope := range key.Scopes {
+		for _, permission := range held {
+			if scope == permission {
+				granted = append(granted, scope)
+				break
 			}
-			return jwtKey, nil
-		})
+		}
+	}
+
+	if err := a.apiKeyRepo.TouchLastUsed(key.ID, now); err != nil {
+		log.Printf("failed to record use of api key %s: %v", key.ID, err)
+	}
+
+	ctx = WithUser(ctx, user)
+	ctx = context.WithValue(ctx, apiKeyContextKey, key)
+	return WithPermissions(ctx, granted), nil
+}
 
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+// activeUser loads the user credentials were issued to; notFound is returned
+// when the user no longer exists
+func (a *Authenticator) activeUser(userID int, notFound *authError) (*entity.User, *authError) {
+	user, err := a.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, notFound
+		}
+		return nil, errAuthFailed
+	}
+	if !user.IsActive {
+		return nil, errInactive
+	}
+	return user, nil
+}
+
+// RequireSession rejects requests authenticated with an API key. Account
+// security settings, including API key management, need an interactive login.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ClaimsFromContext(r.Context()); !ok {
+			http.Error(w, "This endpoint cannot be used with an API key", http.StatusForbidden)
 			return
 		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		// Token is valid; proceed to the next handler
+// RequireVerifiedEmail rejects users who have not confirmed their email
+// address. It must run after the auth middleware.
+func RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := UserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !user.EmailVerified() {
+			http.Error(w, "Email address is not verified", http.StatusForbidden)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequirePermission only lets callers through whose token grants every
+// listed permission. It must run after the auth middleware.
+func RequirePermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := UserFromContext(r.Context()); !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			for _, permission := range permissions {
+				if !HasPermission(r.Context(), permission) {
+					http.Error(w, "Forbidden", http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithUser returns a copy of ctx carrying the authenticated user
+func WithUser(ctx context.Context, user *entity.User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// UserFromContext returns the authenticated user stored by the auth middleware
+func UserFromContext(ctx context.Context) (*entity.User, bool) {
+	user, ok := ctx.Value(userContextKey).(*entity.User)
+	return user, ok && user != nil
+}
+
+// ClaimsFromContext returns the verified access token claims of the current request
+func ClaimsFromContext(ctx context.Context) (*utils.Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*utils.Claims)
+	return claims, ok && claims != nil
+}
+
+// APIKeyFromContext returns the API key the current request was authenticated with
+func APIKeyFromContext(ctx context.Context) (*entity.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey).(*entity.APIKey)
+	return key, ok && key != nil
+}
+
+// WithPermissions returns a copy of ctx carrying the caller's granted permissions
+func WithPermissions(ctx context.Context, permissions []string) context.Context {
+	set := make(map[string]struct{}, len(permissions))
+	for _, permission := range permissions {
+		set[permission] = struct{}{}
+	}
+	return context.WithValue(ctx, permissionsContextKey, set)
+}
+
+// HasPermission reports whether the caller was granted a permission
+func HasPermission(ctx context.Context, permission string) bool {
+	set, _ := ctx.Value(permissionsContextKey).(map[string]struct{})
+	_, ok := set[permission]
+	return ok
+}
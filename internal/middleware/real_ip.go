@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIP rewrites r.RemoteAddr to the client address reported by a reverse
+// proxy. Only enable it when the server is reachable exclusively through a
+// single trusted proxy; otherwise clients could spoof their address. The
+// proxy-appended (last) X-Forwarded-For entry is used, as earlier entries are
+// supplied by the client.
+func RealIP(trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !trustProxy {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := forwardedIP(r); ip != "" {
+				r.RemoteAddr = net.JoinHostPort(ip, "0")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func forwardedIP(r *http.Request) string {
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	forwarded := r.Header.Values("X-Forwarded-For")
+	if len(forwarded) == 0 {
+		return ""
+	}
+	hops := strings.Split(forwarded[len(forwarded)-1], ",")
+	if ip := strings.TrimSpace(hops[len(hops)-1]); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return ""
+}
+
+// ClientIP returns the IP address of the caller, as rewritten by RealIP
+func ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
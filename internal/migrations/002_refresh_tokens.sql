@@ -0,0 +1,16 @@
+-- Refresh tokens issued on login and rotated on every use.
+-- Tokens are stored as SHA-256 hashes; all tokens rotated from the same login
+-- share a family_id so that a replayed token can revoke the whole chain.
+CREATE TABLE refresh_tokens (
+                                id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                                family_id UUID NOT NULL,
+                                token_hash VARCHAR(64) UNIQUE NOT NULL,
+                                expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                                used_at TIMESTAMP WITH TIME ZONE,
+                                revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_refresh_tokens_user ON refresh_tokens(user_id);
+CREATE INDEX idx_refresh_tokens_family ON refresh_tokens(family_id);
@@ -0,0 +1,16 @@
+-- Access token revocation.
+-- token_version is embedded in every access token; incrementing it logs the
+-- user out of all sessions at once.
+ALTER TABLE users ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+-- Individually revoked access tokens, keyed by their jti claim. Rows can be
+-- deleted once expires_at has passed.
+CREATE TABLE revoked_tokens (
+                                jti VARCHAR(64) PRIMARY KEY,
+                                user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                                expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                revoked_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_revoked_tokens_user ON revoked_tokens(user_id);
+CREATE INDEX idx_revoked_tokens_expires ON revoked_tokens(expires_at);
@@ -0,0 +1,13 @@
+-- Single-use tokens sent to users by email (password reset, ...).
+-- Only a SHA-256 hash of the token is stored.
+CREATE TABLE action_tokens (
+                               id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                               user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                               purpose VARCHAR(32) NOT NULL,
+                               token_hash VARCHAR(64) UNIQUE NOT NULL,
+                               expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                               created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                               used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_action_tokens_user_purpose ON action_tokens(user_id, purpose);
@@ -0,0 +1,7 @@
+-- Email verification. New accounts start unverified; accounts created before
+-- verification existed are treated as verified at their creation time.
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE users SET email_verified_at = COALESCE(created_at, CURRENT_TIMESTAMP);
+
+CREATE INDEX idx_action_tokens_user_purpose_created ON action_tokens(user_id, purpose, created_at);
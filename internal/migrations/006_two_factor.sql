@@ -0,0 +1,15 @@
+-- TOTP two-factor authentication
+ALTER TABLE users ADD COLUMN totp_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN totp_enabled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- One-time recovery codes, stored as SHA-256 hashes
+CREATE TABLE recovery_codes (
+                                id SERIAL PRIMARY KEY,
+                                user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                                code_hash VARCHAR(64) NOT NULL,
+                                created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                                used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_recovery_codes_user ON recovery_codes(user_id);
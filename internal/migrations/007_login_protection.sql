@@ -0,0 +1,17 @@
+-- Failed login tracking and temporary account lockout
+ALTER TABLE users ADD COLUMN failed_login_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN last_failed_login_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP WITH TIME ZONE;
+
+-- Security audit log
+CREATE TABLE audit_logs (
+                            id SERIAL PRIMARY KEY,
+                            user_id INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+                            action VARCHAR(50) NOT NULL,
+                            ip_address VARCHAR(45),
+                            details TEXT,
+                            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_logs_user ON audit_logs(user_id);
+CREATE INDEX idx_audit_logs_action ON audit_logs(action, created_at);
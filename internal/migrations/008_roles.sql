@@ -0,0 +1,56 @@
+-- Role-based access control
+CREATE TABLE roles (
+                       role_id SERIAL PRIMARY KEY,
+                       name VARCHAR(50) UNIQUE NOT NULL,
+                       description TEXT
+);
+
+CREATE TABLE permissions (
+                             permission_id SERIAL PRIMARY KEY,
+                             name VARCHAR(100) UNIQUE NOT NULL,
+                             description TEXT
+);
+
+CREATE TABLE role_permissions (
+                                  role_id INTEGER REFERENCES roles(role_id) ON DELETE CASCADE,
+                                  permission_id INTEGER REFERENCES permissions(permission_id) ON DELETE CASCADE,
+                                  PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE user_roles (
+                            user_id INTEGER REFERENCES users(user_id) ON DELETE CASCADE,
+                            role_id INTEGER REFERENCES roles(role_id) ON DELETE CASCADE,
+                            granted_by INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+                            granted_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                            PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX idx_user_roles_role ON user_roles(role_id);
+
+INSERT INTO roles (name, description) VALUES
+    ('user', 'Regular member'),
+    ('moderator', 'Can suspend accounts and moderate content'),
+    ('admin', 'Full administrative access');
+
+INSERT INTO permissions (name, description) VALUES
+    ('profile:read', 'Read own profile'),
+    ('profile:write', 'Update own profile'),
+    ('books:read', 'Read books'),
+    ('books:write', 'Create, update and delete own books'),
+    ('users:read', 'List and inspect all accounts'),
+    ('users:suspend', 'Suspend and reinstate accounts'),
+    ('users:manage_roles', 'Assign roles to accounts'),
+    ('audit:read', 'Read the security audit log');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id
+FROM roles r
+         JOIN permissions p ON
+    (r.name = 'user' AND p.name IN ('profile:read', 'profile:write', 'books:read', 'books:write'))
+        OR (r.name = 'moderator' AND p.name IN ('profile:read', 'profile:write', 'books:read', 'books:write',
+                                                'users:read', 'users:suspend'))
+        OR r.name = 'admin';
+
+-- Every existing account is a regular member
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.user_id, r.role_id FROM users u CROSS JOIN roles r WHERE r.name = 'user';
@@ -0,0 +1,27 @@
+-- OpenID Connect login
+CREATE TABLE external_identities (
+                                     id SERIAL PRIMARY KEY,
+                                     user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                                     provider VARCHAR(50) NOT NULL,
+                                     subject VARCHAR(255) NOT NULL,
+                                     email VARCHAR(255),
+                                     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                                     last_login_at TIMESTAMP WITH TIME ZONE,
+                                     UNIQUE (provider, subject),
+                                     UNIQUE (user_id, provider)
+);
+
+CREATE INDEX idx_external_identities_user ON external_identities(user_id);
+
+-- Pending authorization requests; rows are consumed by the callback
+CREATE TABLE oidc_login_states (
+                                   state_hash VARCHAR(64) PRIMARY KEY,
+                                   provider VARCHAR(50) NOT NULL,
+                                   code_verifier VARCHAR(128) NOT NULL,
+                                   nonce VARCHAR(64) NOT NULL,
+                                   user_id INTEGER REFERENCES users(user_id) ON DELETE CASCADE,
+                                   expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                   created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_oidc_login_states_expires ON oidc_login_states(expires_at);
@@ -0,0 +1,16 @@
+-- Personal API keys. Only a SHA-256 hash of the key is stored; scopes is a
+-- space separated list of permission names.
+CREATE TABLE api_keys (
+                          id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                          user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                          name VARCHAR(100) NOT NULL,
+                          prefix VARCHAR(16) NOT NULL,
+                          key_hash VARCHAR(64) UNIQUE NOT NULL,
+                          scopes TEXT NOT NULL,
+                          expires_at TIMESTAMP WITH TIME ZONE,
+                          last_used_at TIMESTAMP WITH TIME ZONE,
+                          created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                          revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_api_keys_user ON api_keys(user_id);
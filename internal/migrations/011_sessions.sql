@@ -0,0 +1,13 @@
+-- One row per login. The id equals the family_id of the session's refresh
+-- tokens; revoking a family revokes the session.
+CREATE TABLE sessions (
+                          id UUID PRIMARY KEY,
+                          user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                          user_agent VARCHAR(512),
+                          ip_address VARCHAR(45),
+                          created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                          last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                          revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_sessions_user ON sessions(user_id);
@@ -0,0 +1,7 @@
+-- Self-service deactivation and deletion. A deactivated account can be
+-- reactivated by its owner; once deletion_scheduled_at has passed the account
+-- is anonymized and deleted.
+ALTER TABLE users ADD COLUMN deactivated_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN deletion_scheduled_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_users_deletion_scheduled ON users(deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
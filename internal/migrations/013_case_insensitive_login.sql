@@ -0,0 +1,81 @@
+-- Usernames and email addresses are unique regardless of case, so that either
+-- can be used to log in. Accounts that only differ by case are resolved first:
+-- the verified (or otherwise the oldest) account keeps the identifier, the
+-- others are renamed. Every change is recorded for support to follow up.
+BEGIN;
+
+CREATE TABLE login_identifier_conflicts (
+                                            id SERIAL PRIMARY KEY,
+                                            user_id INTEGER REFERENCES users(user_id) ON DELETE CASCADE,
+                                            field VARCHAR(20) NOT NULL,
+                                            original_value VARCHAR(255) NOT NULL,
+                                            new_value VARCHAR(255) NOT NULL,
+                                            created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Usernames: duplicates get the user ID appended, e.g. alice_42. Should that
+-- name be taken as well, a counter is added until it is free: alice_42_1.
+DO $$
+DECLARE
+    dup RECORD;
+    suffix TEXT;
+    candidate TEXT;
+    attempt INTEGER;
+BEGIN
+    FOR dup IN
+        SELECT user_id, username
+        FROM (
+            SELECT user_id, username,
+                   ROW_NUMBER() OVER (
+                       PARTITION BY LOWER(username)
+                       ORDER BY (email_verified_at IS NULL), user_id
+                   ) AS n
+            FROM users
+        ) ranked
+        WHERE n > 1
+        ORDER BY user_id
+    LOOP
+        attempt := 0;
+        LOOP
+            suffix := '_' || dup.user_id || CASE WHEN attempt > 0 THEN '_' || attempt ELSE '' END;
+            candidate := LEFT(dup.username, 50 - LENGTH(suffix)) || suffix;
+            EXIT WHEN NOT EXISTS (SELECT 1 FROM users WHERE LOWER(username) = LOWER(candidate));
+            attempt := attempt + 1;
+        END LOOP;
+
+        INSERT INTO login_identifier_conflicts (user_id, field, original_value, new_value)
+        VALUES (dup.user_id, 'username', dup.username, candidate);
+        UPDATE users SET username = candidate WHERE user_id = dup.user_id;
+    END LOOP;
+END $$;
+
+-- Email addresses: duplicates point at the same mailbox, which already owns
+-- the kept account. They get an undeliverable placeholder and lose their
+-- verification; their owners can still log in by username.
+CREATE TEMPORARY TABLE email_renames ON COMMIT DROP AS
+SELECT user_id, email AS original_value,
+       'user' || user_id || '@duplicate.invalid' AS new_value
+FROM (
+    SELECT user_id, email,
+           ROW_NUMBER() OVER (
+               PARTITION BY LOWER(email)
+               ORDER BY (email_verified_at IS NULL), user_id
+           ) AS n
+    FROM users
+) ranked
+WHERE n > 1;
+
+INSERT INTO login_identifier_conflicts (user_id, field, original_value, new_value)
+SELECT user_id, 'email', original_value, new_value FROM email_renames;
+
+UPDATE users u SET email = r.new_value, email_verified_at = NULL
+FROM email_renames r
+WHERE u.user_id = r.user_id;
+
+-- Emails are stored lowercase; usernames keep the case chosen by the user
+UPDATE users SET email = LOWER(email) WHERE email <> LOWER(email);
+
+CREATE UNIQUE INDEX idx_users_username_lower ON users(LOWER(username));
+CREATE UNIQUE INDEX idx_users_email_lower ON users(LOWER(email));
+
+COMMIT;
@@ -0,0 +1,4 @@
+-- Profile pictures. The thumbnails live in file storage under
+-- avatars/<user_id>/<avatar_id>/<size>.jpg; avatar_urls maps sizes to their URLs.
+ALTER TABLE users ADD COLUMN avatar_id VARCHAR(36);
+ALTER TABLE users ADD COLUMN avatar_urls JSONB;
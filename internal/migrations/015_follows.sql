@@ -0,0 +1,19 @@
+-- Social graph: follower_id follows followee_id
+CREATE TABLE follows (
+                         follower_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                         followee_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                         created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                         PRIMARY KEY (follower_id, followee_id),
+                         CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX idx_follows_followee ON follows(followee_id, created_at);
+
+-- The feed is assembled on read from the recent activity of each followed
+-- user; these indexes let every part of it stop after a page of rows
+CREATE INDEX idx_books_owner_created ON books(owner_id, created_at DESC, book_id DESC);
+CREATE INDEX idx_reviews_reviewer_created ON reviews(reviewer_id, created_at DESC, review_id DESC);
+CREATE INDEX idx_exchanges_requester_completed ON exchanges(requester_id, completed_at DESC, exchange_id DESC)
+    WHERE status = 'Completed';
+CREATE INDEX idx_exchanges_book_completed ON exchanges(book_id, completed_at DESC, exchange_id DESC)
+    WHERE status = 'Completed';
@@ -0,0 +1,11 @@
+-- Users who blocked each other cannot interact, and each one's content is
+-- hidden from the other
+CREATE TABLE user_blocks (
+                             blocker_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                             blocked_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                             created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                             PRIMARY KEY (blocker_id, blocked_id),
+                             CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX idx_user_blocks_blocked ON user_blocks(blocked_id);
@@ -0,0 +1,34 @@
+-- Structured positions in WGS 84 decimal degrees. A user's exact position is
+-- private; books and posts carry a copy snapped to a coarse grid, which is
+-- what nearby search matches and shows. Meeting points of an exchange are
+-- only visible to its two parties and stay exact.
+ALTER TABLE users ADD COLUMN latitude DOUBLE PRECISION;
+ALTER TABLE users ADD COLUMN longitude DOUBLE PRECISION;
+ALTER TABLE books ADD COLUMN latitude DOUBLE PRECISION;
+ALTER TABLE books ADD COLUMN longitude DOUBLE PRECISION;
+ALTER TABLE posts ADD COLUMN latitude DOUBLE PRECISION;
+ALTER TABLE posts ADD COLUMN longitude DOUBLE PRECISION;
+ALTER TABLE exchanges ADD COLUMN meeting_latitude DOUBLE PRECISION;
+ALTER TABLE exchanges ADD COLUMN meeting_longitude DOUBLE PRECISION;
+
+ALTER TABLE users ADD CONSTRAINT valid_user_coordinates
+    CHECK ((latitude IS NULL) = (longitude IS NULL)
+        AND latitude BETWEEN -90 AND 90 AND longitude BETWEEN -180 AND 180);
+ALTER TABLE books ADD CONSTRAINT valid_book_coordinates
+    CHECK ((latitude IS NULL) = (longitude IS NULL)
+        AND latitude BETWEEN -90 AND 90 AND longitude BETWEEN -180 AND 180);
+ALTER TABLE posts ADD CONSTRAINT valid_post_coordinates
+    CHECK ((latitude IS NULL) = (longitude IS NULL)
+        AND latitude BETWEEN -90 AND 90 AND longitude BETWEEN -180 AND 180);
+ALTER TABLE exchanges ADD CONSTRAINT valid_meeting_coordinates
+    CHECK ((meeting_latitude IS NULL) = (meeting_longitude IS NULL)
+        AND meeting_latitude BETWEEN -90 AND 90 AND meeting_longitude BETWEEN -180 AND 180);
+
+-- Nearby search first matches a bounding box around the searched circle:
+-- the latitude range narrows the index to a band and longitude is checked
+-- from the index entries, so exact distances are only computed for the
+-- candidates inside the box. No PostGIS is needed.
+CREATE INDEX idx_books_available_location ON books(latitude, longitude)
+    WHERE is_available AND latitude IS NOT NULL;
+CREATE INDEX idx_posts_published_location ON posts(latitude, longitude)
+    WHERE is_published AND latitude IS NOT NULL;
@@ -0,0 +1,8 @@
+-- ISBNs are stored as bare ISBN-13s. Older rows may hold hyphenated or
+-- ISBN-10 values; lookups match both forms, so only the separators are
+-- removed here.
+UPDATE books SET isbn = UPPER(REPLACE(REPLACE(isbn, '-', ''), ' ', ''))
+    WHERE isbn IS NOT NULL;
+UPDATE books SET isbn = NULL WHERE isbn = '';
+
+CREATE INDEX idx_books_isbn ON books(isbn) WHERE isbn IS NOT NULL;
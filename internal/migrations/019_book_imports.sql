@@ -0,0 +1,26 @@
+-- Bulk library imports. A job is created when a file is uploaded and its
+-- rows are added in the background; errors lists per-row problems as
+-- [{"row": 3, "message": "...", "skipped": true}]. updated_at advances while
+-- a job runs, so jobs abandoned by a restart can be told apart.
+CREATE TABLE import_jobs (
+                             id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                             user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+                             format VARCHAR(20) NOT NULL,
+                             status VARCHAR(20) NOT NULL DEFAULT 'pending'
+                                 CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+                             total_rows INTEGER NOT NULL DEFAULT 0,
+                             processed_rows INTEGER NOT NULL DEFAULT 0,
+                             imported INTEGER NOT NULL DEFAULT 0,
+                             skipped INTEGER NOT NULL DEFAULT 0,
+                             failed INTEGER NOT NULL DEFAULT 0,
+                             errors JSONB,
+                             created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                             updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                             finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_import_jobs_user ON import_jobs(user_id, created_at DESC);
+
+-- A user runs one import at a time
+CREATE UNIQUE INDEX idx_import_jobs_unfinished ON import_jobs(user_id)
+    WHERE status IN ('pending', 'running');
@@ -0,0 +1,15 @@
+-- Condition history: one row when a book is listed and one for every
+-- change of its condition afterwards
+CREATE TABLE book_condition_changes (
+                                        change_id SERIAL PRIMARY KEY,
+                                        book_id INTEGER NOT NULL REFERENCES books(book_id) ON DELETE CASCADE,
+                                        condition book_condition NOT NULL,
+                                        note TEXT,
+                                        changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_book_condition_changes_book ON book_condition_changes(book_id, changed_at DESC, change_id DESC);
+
+-- Start the history of existing books with their current condition
+INSERT INTO book_condition_changes (book_id, condition, changed_at)
+SELECT book_id, condition, created_at FROM books;
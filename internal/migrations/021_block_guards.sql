@@ -0,0 +1,73 @@
+-- Users who blocked each other cannot message each other, request exchanges
+-- of each other's books, comment on each other's content or review each
+-- other. The checks run on insert so every code path is covered; the app
+-- maps SQLSTATE BLK01 to its "blocked" error.
+CREATE OR REPLACE FUNCTION users_blocked(a INTEGER, b INTEGER)
+    RETURNS BOOLEAN AS $$
+SELECT a IS NOT NULL AND b IS NOT NULL AND EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = a AND blocked_id = b) OR (blocker_id = b AND blocked_id = a)
+);
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION reject_blocked_interaction()
+    RETURNS TRIGGER AS $$
+DECLARE
+    actor INTEGER;
+    targets INTEGER[];
+    target INTEGER;
+BEGIN
+    IF TG_TABLE_NAME = 'messages' THEN
+        actor := NEW.sender_id;
+        targets := ARRAY[NEW.receiver_id];
+    ELSIF TG_TABLE_NAME = 'exchanges' THEN
+        actor := NEW.requester_id;
+        targets := ARRAY(SELECT owner_id FROM books WHERE book_id = NEW.book_id);
+    ELSIF TG_TABLE_NAME = 'reviews' THEN
+        actor := NEW.reviewer_id;
+        targets := ARRAY[NEW.reviewed_user_id];
+    ELSIF TG_TABLE_NAME = 'comments' THEN
+        actor := NEW.user_id;
+        targets := CASE NEW.target_type
+            WHEN 'user' THEN ARRAY[NEW.target_id]
+            WHEN 'book' THEN ARRAY(SELECT owner_id FROM books WHERE book_id = NEW.target_id)
+            WHEN 'review' THEN ARRAY(SELECT reviewer_id FROM reviews WHERE review_id = NEW.target_id)
+            WHEN 'exchange' THEN ARRAY(
+                SELECT e.requester_id FROM exchanges e WHERE e.exchange_id = NEW.target_id
+                UNION ALL
+                SELECT b.owner_id FROM exchanges e JOIN books b ON b.book_id = e.book_id
+                WHERE e.exchange_id = NEW.target_id)
+        END;
+        IF NEW.parent_comment_id IS NOT NULL THEN
+            targets := targets || ARRAY(SELECT user_id FROM comments WHERE comment_id = NEW.parent_comment_id);
+        END IF;
+    END IF;
+
+    FOREACH target IN ARRAY COALESCE(targets, ARRAY[]::INTEGER[]) LOOP
+        IF users_blocked(actor, target) THEN
+            RAISE EXCEPTION 'you cannot interact with this user' USING ERRCODE = 'BLK01';
+        END IF;
+    END LOOP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER reject_blocked_messages
+    BEFORE INSERT ON messages
+    FOR EACH ROW
+EXECUTE FUNCTION reject_blocked_interaction();
+
+CREATE TRIGGER reject_blocked_exchanges
+    BEFORE INSERT ON exchanges
+    FOR EACH ROW
+EXECUTE FUNCTION reject_blocked_interaction();
+
+CREATE TRIGGER reject_blocked_reviews
+    BEFORE INSERT ON reviews
+    FOR EACH ROW
+EXECUTE FUNCTION reject_blocked_interaction();
+
+CREATE TRIGGER reject_blocked_comments
+    BEFORE INSERT ON comments
+    FOR EACH ROW
+EXECUTE FUNCTION reject_blocked_interaction();
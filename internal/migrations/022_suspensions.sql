@@ -0,0 +1,7 @@
+-- Suspensions are recorded apart from self-deactivation, so that an owner
+-- reactivating their account cannot lift a moderator's suspension
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP WITH TIME ZONE;
+
+-- Until now a disabled account the owner did not deactivate was suspended
+UPDATE users SET suspended_at = CURRENT_TIMESTAMP
+    WHERE NOT is_active AND deactivated_at IS NULL;
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+var (
+	ErrActionTokenNotFound = errors.New("token not found or already used")
+)
+
+// ActionTokenRepository defines methods for single-use action token persistence
+type ActionTokenRepository interface {
+	Create(token *entity.ActionToken) error
+	FindUnused(hash, purpose string) (*entity.ActionToken, error)
+	Consume(id uuid.UUID) error
+	InvalidateForUser(userID int, purpose string) error
+	CountIssuedSince(userID int, purpose string, since time.Time) (int64, error)
+}
+
+// GormActionTokenRepository is a GORM implementation of ActionTokenRepository
+type GormActionTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewActionTokenRepository creates a new GormActionTokenRepository
+func NewActionTokenRepository(db *gorm.DB) ActionTokenRepository {
+	return &GormActionTokenRepository{db: db}
+}
+
+// Create stores a new action token
+func (repo *GormActionTokenRepository) Create(token *entity.ActionToken) error {
+	return repo.db.Create(token).Error
+}
+
+// FindUnused retrieves an unused token by hash and purpose
+func (repo *GormActionTokenRepository) FindUnused(hash, purpose string) (*entity.ActionToken, error) {
+	var token entity.ActionToken
+	err := repo.db.Where("token_hash = ? AND purpose = ? AND used_at IS NULL", hash, purpose).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrActionTokenNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Consume marks a token as used. Only the first caller succeeds, so a token
+// cannot be redeemed twice even by concurrent requests.
+func (repo *GormActionTokenRepository) Consume(id uuid.UUID) error {
+	result := repo.db.Model(&entity.ActionToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrActionTokenNotFound
+	}
+	return nil
+}
+
+// InvalidateForUser marks all of a user's unused tokens for a purpose as used
+func (repo *GormActionTokenRepository) InvalidateForUser(userID int, purpose string) error {
+	return repo.db.Model(&entity.ActionToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
+
+// CountIssuedSince counts the tokens issued to a user for a purpose after a point in time
+func (repo *GormActionTokenRepository) CountIssuedSince(userID int, purpose string, since time.Time) (int64, error) {
+	var count int64
+	err := repo.db.Model(&entity.ActionToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at > ?", userID, purpose, since).
+		Count(&count).Error
+	return count, err
+}
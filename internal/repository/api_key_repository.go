@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// lastUsedResolution limits how often last_used_at is written for a busy key
+const lastUsedResolution = time.Minute
+
+// APIKeyRepository defines methods for API key persistence
+type APIKeyRepository interface {
+	Create(key *entity.APIKey) error
+	FindByHash(hash string) (*entity.APIKey, error)
+	ListActiveForUser(userID int) ([]entity.APIKey, error)
+	CountActiveForUser(userID int) (int64, error)
+	Revoke(userID int, id uuid.UUID) error
+	TouchLastUsed(id uuid.UUID, at time.Time) error
+}
+
+// GormAPIKeyRepository is a GORM implementation of APIKeyRepository
+type GormAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository creates a new GormAPIKeyRepository
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &GormAPIKeyRepository{db: db}
+}
+
+// Create stores a new API key
+func (repo *GormAPIKeyRepository) Create(key *entity.APIKey) error {
+	return repo.db.Create(key).Error
+}
+
+// FindByHash retrieves a key by the hash of its plaintext value
+func (repo *GormAPIKeyRepository) FindByHash(hash string) (*entity.APIKey, error) {
+	var key entity.APIKey
+	if err := repo.db.Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListActiveForUser returns a user's keys that have not been revoked, newest first
+func (repo *GormAPIKeyRepository) ListActiveForUser(userID int) ([]entity.APIKey, error) {
+	var keys []entity.APIKey
+	err := repo.db.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// CountActiveForUser counts a user's keys that have not been revoked
+func (repo *GormAPIKeyRepository) CountActiveForUser(userID int) (int64, error) {
+	var count int64
+	err := repo.db.Model(&entity.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// Revoke disables one of a user's keys
+func (repo *GormAPIKeyRepository) Revoke(userID int, id uuid.UUID) error {
+	result := repo.db.Model(&entity.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed records a use of the key, at most once per lastUsedResolution
+func (repo *GormAPIKeyRepository) TouchLastUsed(id uuid.UUID, at time.Time) error {
+	return repo.db.Model(&entity.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, at.Add(-lastUsedResolution)).
+		Update("last_used_at", at).Error
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+// AuditRepository defines methods for audit log persistence
+type AuditRepository interface {
+	Record(entry *entity.AuditLog) error
+	List(limit, offset int) ([]entity.AuditLog, int64, error)
+}
+
+// GormAuditRepository is a GORM implementation of AuditRepository
+type GormAuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new GormAuditRepository
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &GormAuditRepository{db: db}
+}
+
+// Record appends an entry to the audit log
+func (repo *GormAuditRepository) Record(entry *entity.AuditLog) error {
+	return repo.db.Create(entry).Error
+}
+
+// List returns a page of audit entries, newest first, with the total number of entries
+func (repo *GormAuditRepository) List(limit, offset int) ([]entity.AuditLog, int64, error) {
+	var entries []entity.AuditLog
+	var total int64
+	if err := repo.db.Model(&entity.AuditLog{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := repo.db.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+// ErrBlocked is returned by inserts the database rejects because one of the
+// users involved blocked the other (see migrations/021_block_guards.sql)
+var ErrBlocked = errors.New("you cannot interact with this user")
+
+// HiddenUsersSQL selects, as user_id, the users @viewer blocked or was
+// blocked by. Queries showing other users' content leave these users out.
+const HiddenUsersSQL = `SELECT blocked_id AS user_id FROM user_blocks WHERE blocker_id = @viewer
+    UNION
+    SELECT blocker_id FROM user_blocks WHERE blocked_id = @viewer`
+
+// blockedInteractionCode is the SQLSTATE raised by the block guard triggers
+const blockedInteractionCode = "BLK01"
+
+// RegisterBlockGuard makes inserts rejected by the block guard triggers on
+// messages, exchanges, reviews and comments fail with ErrBlocked, whichever
+// repository runs them
+func RegisterBlockGuard(db *gorm.DB) error {
+	translate := func(tx *gorm.DB) {
+		var pgErr *pgconn.PgError
+		if errors.As(tx.Error, &pgErr) && pgErr.Code == blockedInteractionCode {
+			tx.Error = ErrBlocked
+		}
+	}
+	if err := db.Callback().Create().After("gorm:create").Register("blocks:translate", translate); err != nil {
+		return err
+	}
+	return db.Callback().Raw().After("gorm:raw").Register("blocks:translate", translate)
+}
+
+// BlockRepository defines methods for block list persistence
+type BlockRepository interface {
+	Block(blockerID, blockedID int) error
+	Unblock(blockerID, blockedID int) error
+	ListBlocked(blockerID, limit, offset int) ([]entity.User, int64, error)
+	// EitherBlocked reports whether one of the users blocked the other
+	EitherBlocked(userID, otherID int) (bool, error)
+}
+
+// GormBlockRepository is a GORM implementation of BlockRepository
+type GormBlockRepository struct {
+	db *gorm.DB
+}
+
+// NewBlockRepository creates a new GormBlockRepository
+func NewBlockRepository(db *gorm.DB) BlockRepository {
+	return &GormBlockRepository{db: db}
+}
+
+// Block adds a block and ends any follows between the two users
+func (repo *GormBlockRepository) Block(blockerID, blockedID int) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		block := entity.UserBlock{BlockerID: blockerID, BlockedID: blockedID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&block).Error; err != nil {
+			return err
+		}
+		return tx.Where("(follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)",
+			blockerID, blockedID, blockedID, blockerID).Delete(&entity.Follow{}).Error
+	})
+}
+
+// Unblock removes a block, if any
+func (repo *GormBlockRepository) Unblock(blockerID, blockedID int) error {
+	return repo.db.Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).Delete(&entity.UserBlock{}).Error
+}
+
+// ListBlocked returns a page of the active users blockerID blocked, most recent first
+func (repo *GormBlockRepository) ListBlocked(blockerID, limit, offset int) ([]entity.User, int64, error) {
+	query := repo.db.Model(&entity.User{}).
+		Joins("JOIN user_blocks ON user_blocks.blocked_id = users.user_id").
+		Where("user_blocks.blocker_id = ? AND users.is_active = ?", blockerID, true)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []entity.User
+	err := query.Order("user_blocks.created_at DESC, users.user_id").Limit(limit).Offset(offset).Find(&users).Error
+	return users, total, err
+}
+
+func (repo *GormBlockRepository) EitherBlocked(userID, otherID int) (bool, error) {
+	var count int64
+	err := repo.db.Model(&entity.UserBlock{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userID, otherID, otherID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// notHiddenFrom scopes a query to rows whose user column is neither blocked
+// by viewerID nor blocking them
+func notHiddenFrom(column string, viewerID int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" NOT IN ("+HiddenUsersSQL+")", sql.Named("viewer", viewerID))
+	}
+}
@@ -1 +1,244 @@
 package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+var (
+	ErrBookNotFound   = errors.New("book not found")
+	ErrBookInExchange = errors.New("book is part of an open exchange")
+)
+
+// BookRepository defines methods for book data persistence
+type BookRepository interface {
+	Create(book *entity.Book) error
+	FindByID(bookID int) (*entity.Book, error)
+	ListByOwner(ownerID int, availableOnly bool, limit, offset int) ([]entity.Book, int64, error)
+	ListAvailableByISBN(viewerID int, isbns []string, limit, offset int) ([]entity.Book, int64, error)
+	ListOwnerISBNs(ownerID int) ([]string, error)
+	Update(bookID int, update entity.UpdateBookRequest) error
+	SetAvailable(bookID int, available bool) error
+	Delete(bookID int) error
+	RecordCondition(change *entity.BookConditionChange) error
+	ListConditionHistory(bookID, limit, offset int) ([]entity.BookConditionChange, int64, error)
+}
+
+// GormBookRepository is a GORM implementation of BookRepository
+type GormBookRepository struct {
+	db *gorm.DB
+}
+
+// NewBookRepository creates a new GormBookRepository
+func NewBookRepository(db *gorm.DB) BookRepository {
+	return &GormBookRepository{db: db}
+}
+
+// Create stores a new book and starts its condition history
+func (repo *GormBookRepository) Create(book *entity.Book) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(book).Error; err != nil {
+			return err
+		}
+		return tx.Create(&entity.BookConditionChange{BookID: book.BookID, Condition: book.Condition}).Error
+	})
+}
+
+// FindByID retrieves a book of an active user, with the owner's username
+func (repo *GormBookRepository) FindByID(bookID int) (*entity.Book, error) {
+	var book entity.Book
+	if err := repo.withOwner().Where("books.book_id = ?", bookID).First(&book).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookNotFound
+		}
+		return nil, err
+	}
+	return &book, nil
+}
+
+// ListByOwner returns a page of a user's books, newest first
+func (repo *GormBookRepository) ListByOwner(ownerID int, availableOnly bool, limit, offset int) ([]entity.Book, int64, error) {
+	query := repo.withOwner().Where("books.owner_id = ?", ownerID)
+	if availableOnly {
+		query = query.Where("books.is_available = ?", true)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	books := []entity.Book{}
+	err := query.Order("books.created_at DESC, books.book_id DESC").Limit(limit).Offset(offset).Find(&books).Error
+	return books, total, err
+}
+
+// ListAvailableByISBN returns a page of available books stored under any of
+// the given ISBNs, newest first. Books of users blocked either way by
+// viewerID are left out.
+func (repo *GormBookRepository) ListAvailableByISBN(viewerID int, isbns []string, limit, offset int) ([]entity.Book, int64, error) {
+	query := repo.withOwner().
+		Where("books.isbn IN ? AND books.is_available = ?", isbns, true).
+		Scopes(notHiddenFrom("books.owner_id", viewerID))
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	books := []entity.Book{}
+	err := query.Order("books.created_at DESC, books.book_id DESC").Limit(limit).Offset(offset).Find(&books).Error
+	return books, total, err
+}
+
+// ListOwnerISBNs returns the ISBNs of the owner's books, as stored
+func (repo *GormBookRepository) ListOwnerISBNs(ownerID int) ([]string, error) {
+	var isbns []string
+	err := repo.db.Model(&entity.Book{}).Where("owner_id = ? AND isbn IS NOT NULL", ownerID).Pluck("isbn", &isbns).Error
+	return isbns, err
+}
+
+// withOwner selects books of active users along with the owner's username
+func (repo *GormBookRepository) withOwner() *gorm.DB {
+	return repo.db.Model(&entity.Book{}).
+		Select("books.*, users.username AS owner").
+		Joins("JOIN users ON users.user_id = books.owner_id").
+		Where("users.is_active = ?", true)
+}
+
+// Update stores the book fields set in update. A new condition is added to
+// the book's condition history.
+func (repo *GormBookRepository) Update(bookID int, update entity.UpdateBookRequest) error {
+	changes := map[string]interface{}{}
+	if update.Title != nil {
+		changes["title"] = *update.Title
+	}
+	if update.Author != nil {
+		changes["author"] = *update.Author
+	}
+	if update.ISBN != nil {
+		if *update.ISBN == "" {
+			changes["isbn"] = nil
+		} else {
+			changes["isbn"] = *update.ISBN
+		}
+	}
+	if update.Condition != nil {
+		changes["condition"] = *update.Condition
+	}
+	if update.Description != nil {
+		changes["description"] = *update.Description
+	}
+	if update.Genre != nil {
+		changes["genre"] = *update.Genre
+	}
+	if update.Language != nil {
+		changes["language"] = *update.Language
+	}
+	if update.PublicationYear != nil {
+		if *update.PublicationYear == 0 {
+			changes["publication_year"] = nil
+		} else {
+			changes["publication_year"] = *update.PublicationYear
+		}
+	}
+	if update.ImageURL != nil {
+		changes["image_url"] = *update.ImageURL
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	if update.Condition == nil {
+		return repo.db.Model(&entity.Book{}).Where("book_id = ?", bookID).Updates(changes).Error
+	}
+
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		previous, err := lockCondition(tx, bookID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.Book{}).Where("book_id = ?", bookID).Updates(changes).Error; err != nil {
+			return err
+		}
+		if previous == *update.Condition {
+			return nil
+		}
+		return tx.Create(&entity.BookConditionChange{BookID: bookID, Condition: *update.Condition}).Error
+	})
+}
+
+// RecordCondition sets a book's condition and adds the change, with its
+// note, to the condition history
+func (repo *GormBookRepository) RecordCondition(change *entity.BookConditionChange) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockCondition(tx, change.BookID); err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.Book{}).Where("book_id = ?", change.BookID).Update("condition", change.Condition).Error; err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+}
+
+// ListConditionHistory returns a page of a book's condition changes, newest first
+func (repo *GormBookRepository) ListConditionHistory(bookID, limit, offset int) ([]entity.BookConditionChange, int64, error) {
+	query := repo.db.Model(&entity.BookConditionChange{}).Where("book_id = ?", bookID)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	changes := []entity.BookConditionChange{}
+	err := query.Order("changed_at DESC, change_id DESC").Limit(limit).Offset(offset).Find(&changes).Error
+	return changes, total, err
+}
+
+// lockCondition locks a book row for the rest of tx and returns its
+// current condition, so concurrent changes are recorded in order
+func lockCondition(tx *gorm.DB, bookID int) (string, error) {
+	var book entity.Book
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("condition").Where("book_id = ?", bookID).First(&book).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrBookNotFound
+	}
+	return book.Condition, err
+}
+
+// SetAvailable marks a book as available for exchange or not
+func (repo *GormBookRepository) SetAvailable(bookID int, available bool) error {
+	return repo.db.Model(&entity.Book{}).Where("book_id = ?", bookID).Update("is_available", available).Error
+}
+
+// Delete removes a book. Finished exchanges and posts about it are kept but
+// detached, as when an account is deleted; open exchanges block the deletion.
+func (repo *GormBookRepository) Delete(bookID int) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var open int64
+		if err := tx.Table("exchanges").
+			Where("book_id = ? AND status IN ?", bookID, []string{"Pending", "Accepted"}).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrBookInExchange
+		}
+		if err := tx.Exec("UPDATE exchanges SET book_id = NULL WHERE book_id = ?", bookID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE posts SET book_id = NULL WHERE book_id = ?", bookID).Error; err != nil {
+			return err
+		}
+		result := tx.Where("book_id = ?", bookID).Delete(&entity.Book{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBookNotFound
+		}
+		return nil
+	})
+}
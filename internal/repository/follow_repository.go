@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+// feedQuery merges the recent activity of followed users. Every part walks
+// each followed user's activity through an index on (actor, time) and stops
+// after @limit rows past the cursor, so the cost grows with the number of
+// followed users, not with their history. Exchanges of a followed owner's
+// books are found per book, through an index on (book, time).
+// Activity involving a user blocked either way is left out.
+const feedQuery = `
+WITH hidden AS (` + HiddenUsersSQL + `), followed AS (
+    SELECT f.followee_id AS user_id, u.username
+    FROM follows f
+    JOIN users u ON u.user_id = f.followee_id
+    WHERE f.follower_id = @viewer AND u.is_active
+      AND f.followee_id NOT IN (SELECT user_id FROM hidden)
+)
+SELECT * FROM (
+    (SELECT 'listing'::text AS type, b.book_id AS id, b.created_at AS occurred_at,
+            f.username AS actor, '' AS subject,
+            b.book_id, b.title AS book_title, b.author AS book_author,
+            NULL::integer AS rating, '' AS comment
+     FROM followed f
+     CROSS JOIN LATERAL (
+         SELECT book_id, title, author, created_at
+         FROM books
+         WHERE owner_id = f.user_id
+           AND (created_at, 'listing'::text, book_id) < (@t, @type, @id)
+         ORDER BY created_at DESC, book_id DESC
+         LIMIT @limit
+     ) b
+     ORDER BY b.created_at DESC, b.book_id DESC
+     LIMIT @limit)
+    UNION ALL
+    (SELECT 'review'::text, r.review_id, r.created_at,
+            f.username, COALESCE(s.username, ''),
+            b.book_id, COALESCE(b.title, ''), COALESCE(b.author, ''),
+            r.rating, COALESCE(r.comment, '')
+     FROM followed f
+     CROSS JOIN LATERAL (
+         SELECT review_id, reviewed_user_id, exchange_id, rating, comment, created_at
+         FROM reviews
+         WHERE reviewer_id = f.user_id
+           AND (created_at, 'review'::text, review_id) < (@t, @type, @id)
+           AND COALESCE(reviewed_user_id, 0) NOT IN (SELECT user_id FROM hidden)
+         ORDER BY created_at DESC, review_id DESC
+         LIMIT @limit
+     ) r
+     LEFT JOIN users s ON s.user_id = r.reviewed_user_id
+     LEFT JOIN exchanges e ON e.exchange_id = r.exchange_id
+     LEFT JOIN books b ON b.book_id = e.book_id
+     ORDER BY r.created_at DESC, r.review_id DESC
+     LIMIT @limit)
+    UNION ALL
+    (SELECT 'exchange'::text, e.exchange_id, e.completed_at,
+            CASE WHEN requester.user_id IN (SELECT user_id FROM followed)
+                 THEN requester.username ELSE owner.username END,
+            COALESCE(CASE WHEN requester.user_id IN (SELECT user_id FROM followed)
+                 THEN owner.username ELSE requester.username END, ''),
+            b.book_id, COALESCE(b.title, ''), COALESCE(b.author, ''),
+            NULL::integer, ''
+     FROM (
+         -- Exchanges a followed user requested
+         SELECT x.exchange_id
+         FROM followed f
+         CROSS JOIN LATERAL (
+             SELECT e.exchange_id
+             FROM exchanges e
+             LEFT JOIN books b ON b.book_id = e.book_id
+             WHERE e.requester_id = f.user_id
+               AND e.status = 'Completed' AND e.completed_at IS NOT NULL
+               AND (e.completed_at, 'exchange'::text, e.exchange_id) < (@t, @type, @id)
+               AND COALESCE(b.owner_id, 0) NOT IN (SELECT user_id FROM hidden)
+             ORDER BY e.completed_at DESC, e.exchange_id DESC
+             LIMIT @limit
+         ) x
+         UNION
+         -- Exchanges of books a followed user owns
+         SELECT x.exchange_id
+         FROM followed f
+         CROSS JOIN LATERAL (
+             SELECT e.exchange_id
+             FROM books b
+             JOIN exchanges e ON e.book_id = b.book_id
+             WHERE b.owner_id = f.user_id
+               AND e.status = 'Completed' AND e.completed_at IS NOT NULL
+               AND (e.completed_at, 'exchange'::text, e.exchange_id) < (@t, @type, @id)
+               AND COALESCE(e.requester_id, 0) NOT IN (SELECT user_id FROM hidden)
+             ORDER BY e.completed_at DESC, e.exchange_id DESC
+             LIMIT @limit
+         ) x
+     ) ids
+     JOIN exchanges e ON e.exchange_id = ids.exchange_id
+     LEFT JOIN books b ON b.book_id = e.book_id
+     LEFT JOIN users requester ON requester.user_id = e.requester_id
+     LEFT JOIN users owner ON owner.user_id = b.owner_id
+     ORDER BY e.completed_at DESC, e.exchange_id DESC
+     LIMIT @limit)
+) feed
+ORDER BY occurred_at DESC, type DESC, id DESC
+LIMIT @limit`
+
+// FollowRepository defines methods for the social graph and the activity feed
+type FollowRepository interface {
+	Follow(followerID, followeeID int) error
+	Unfollow(followerID, followeeID int) error
+	ListFollowers(userID, limit, offset int) ([]entity.User, int64, error)
+	ListFollowing(userID, limit, offset int) ([]entity.User, int64, error)
+	Feed(userID int, before entity.FeedCursor, limit int) ([]entity.FeedItem, error)
+}
+
+// GormFollowRepository is a GORM implementation of FollowRepository
+type GormFollowRepository struct {
+	db *gorm.DB
+}
+
+// NewFollowRepository creates a new GormFollowRepository
+func NewFollowRepository(db *gorm.DB) FollowRepository {
+	return &GormFollowRepository{db: db}
+}
+
+// Follow adds a follow; following someone twice is not an error
+func (repo *GormFollowRepository) Follow(followerID, followeeID int) error {
+	follow := entity.Follow{FollowerID: followerID, FolloweeID: followeeID}
+	return repo.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error
+}
+
+// Unfollow removes a follow, if any
+func (repo *GormFollowRepository) Unfollow(followerID, followeeID int) error {
+	return repo.db.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&entity.Follow{}).Error
+}
+
+// ListFollowers returns a page of the active users following userID, most recent first
+func (repo *GormFollowRepository) ListFollowers(userID, limit, offset int) ([]entity.User, int64, error) {
+	return repo.listUsers("follows.follower_id", "follows.followee_id", userID, limit, offset)
+}
+
+// ListFollowing returns a page of the active users userID follows, most recent first
+func (repo *GormFollowRepository) ListFollowing(userID, limit, offset int) ([]entity.User, int64, error) {
+	return repo.listUsers("follows.followee_id", "follows.follower_id", userID, limit, offset)
+}
+
+// listUsers pages through the users in column of the follows matching userID in byColumn
+func (repo *GormFollowRepository) listUsers(column, byColumn string, userID, limit, offset int) ([]entity.User, int64, error) {
+	query := repo.db.Model(&entity.User{}).
+		Joins("JOIN follows ON "+column+" = users.user_id").
+		Where(byColumn+" = ? AND users.is_active = ?", userID, true)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []entity.User
+	err := query.Order("follows.created_at DESC, users.user_id").Limit(limit).Offset(offset).Find(&users).Error
+	return users, total, err
+}
+
+// Feed returns up to limit items older than the cursor, newest first
+func (repo *GormFollowRepository) Feed(userID int, before entity.FeedCursor, limit int) ([]entity.FeedItem, error) {
+	items := []entity.FeedItem{}
+	err := repo.db.Raw(feedQuery, map[string]interface{}{
+		"viewer": userID,
+		"t":      before.OccurredAt,
+		"type":   before.Type,
+		"id":     before.ID,
+		"limit":  limit,
+	}).Scan(&items).Error
+	return items, err
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+var (
+	ErrIdentityNotFound   = errors.New("external identity not found")
+	ErrIdentityLinked     = errors.New("external identity already linked")
+	ErrLoginStateNotFound = errors.New("login state not found or already used")
+)
+
+// IdentityRepository defines methods for external identity and OIDC login state persistence
+type IdentityRepository interface {
+	Find(provider, subject string) (*entity.ExternalIdentity, error)
+	Create(identity *entity.ExternalIdentity) error
+	ListForUser(userID int) ([]entity.ExternalIdentity, error)
+	TouchLogin(id int) error
+	CreateState(state *entity.OIDCLoginState) error
+	ConsumeState(stateHash string) (*entity.OIDCLoginState, error)
+	DeleteExpiredStates() error
+}
+
+// GormIdentityRepository is a GORM implementation of IdentityRepository
+type GormIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewIdentityRepository creates a new GormIdentityRepository
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return &GormIdentityRepository{db: db}
+}
+
+// Find retrieves the identity a provider knows as subject
+func (repo *GormIdentityRepository) Find(provider, subject string) (*entity.ExternalIdentity, error) {
+	var identity entity.ExternalIdentity
+	if err := repo.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// Create links an identity to a user. Both the provider account and the
+// user's slot for that provider must still be free.
+func (repo *GormIdentityRepository) Create(identity *entity.ExternalIdentity) error {
+	result := repo.db.Clauses(clause.OnConflict{DoNothing: true}).Create(identity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdentityLinked
+	}
+	return nil
+}
+
+// ListForUser returns all identities linked to a user
+func (repo *GormIdentityRepository) ListForUser(userID int) ([]entity.ExternalIdentity, error) {
+	var identities []entity.ExternalIdentity
+	err := repo.db.Where("user_id = ?", userID).Order("provider").Find(&identities).Error
+	return identities, err
+}
+
+// TouchLogin records a login through an identity
+func (repo *GormIdentityRepository) TouchLogin(id int) error {
+	return repo.db.Model(&entity.ExternalIdentity{}).Where("id = ?", id).Update("last_login_at", time.Now()).Error
+}
+
+// CreateState stores a pending authorization request
+func (repo *GormIdentityRepository) CreateState(state *entity.OIDCLoginState) error {
+	return repo.db.Create(state).Error
+}
+
+// ConsumeState deletes and returns an unexpired login state, so that each
+// state can complete at most one login
+func (repo *GormIdentityRepository) ConsumeState(stateHash string) (*entity.OIDCLoginState, error) {
+	var states []entity.OIDCLoginState
+	err := repo.db.Clauses(clause.Returning{}).
+		Where("state_hash = ? AND expires_at > ?", stateHash, time.Now()).
+		Delete(&states).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, ErrLoginStateNotFound
+	}
+	return &states[0], nil
+}
+
+// DeleteExpiredStates purges abandoned authorization requests
+func (repo *GormIdentityRepository) DeleteExpiredStates() error {
+	return repo.db.Where("expires_at <= ?", time.Now()).Delete(&entity.OIDCLoginState{}).Error
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+var (
+	ErrImportNotFound   = errors.New("import not found")
+	ErrImportInProgress = errors.New("an import is already in progress")
+)
+
+// unfinishedImportIndex allows one pending or running job per user
+const unfinishedImportIndex = "idx_import_jobs_unfinished"
+
+// ImportRepository defines methods for bulk import job persistence
+type ImportRepository interface {
+	Create(job *entity.ImportJob) error
+	Save(job *entity.ImportJob) error
+	FindByID(userID int, id uuid.UUID) (*entity.ImportJob, error)
+	ListByUser(userID, limit, offset int) ([]entity.ImportJob, int64, error)
+	// FailStale fails pending or running jobs not updated since before,
+	// such as those of a stopped server
+	FailStale(before time.Time, message string) (int64, error)
+}
+
+// GormImportRepository is a GORM implementation of ImportRepository
+type GormImportRepository struct {
+	db *gorm.DB
+}
+
+// NewImportRepository creates a new GormImportRepository
+func NewImportRepository(db *gorm.DB) ImportRepository {
+	return &GormImportRepository{db: db}
+}
+
+// Create stores a new job, or returns ErrImportInProgress if the user has
+// a pending or running one
+func (repo *GormImportRepository) Create(job *entity.ImportJob) error {
+	err := repo.db.Create(job).Error
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == unfinishedImportIndex {
+		return ErrImportInProgress
+	}
+	return err
+}
+
+// Save stores the status and progress of a job
+func (repo *GormImportRepository) Save(job *entity.ImportJob) error {
+	return repo.db.Model(job).Select("status", "total_rows", "processed_rows", "imported", "skipped", "failed", "errors", "finished_at", "updated_at").Updates(job).Error
+}
+
+// FindByID retrieves a job of the user
+func (repo *GormImportRepository) FindByID(userID int, id uuid.UUID) (*entity.ImportJob, error) {
+	var job entity.ImportJob
+	if err := repo.db.Where("id = ? AND user_id = ?", id, userID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImportNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListByUser returns a page of the user's jobs, newest first. Row errors are
+// left out; they are only loaded with a single job.
+func (repo *GormImportRepository) ListByUser(userID, limit, offset int) ([]entity.ImportJob, int64, error) {
+	query := repo.db.Model(&entity.ImportJob{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	jobs := []entity.ImportJob{}
+	err := query.Omit("errors").Order("created_at DESC").Limit(limit).Offset(offset).Find(&jobs).Error
+	return jobs, total, err
+}
+
+func (repo *GormImportRepository) FailStale(before time.Time, message string) (int64, error) {
+	result := repo.db.Model(&entity.ImportJob{}).
+		Where("status IN ? AND updated_at < ?", []string{entity.ImportPending, entity.ImportRunning}, before).
+		Updates(map[string]interface{}{
+			"status":      entity.ImportFailed,
+			"finished_at": time.Now(),
+			"errors":      gorm.Expr("COALESCE(errors, '[]'::jsonb) || CAST(? AS jsonb)", entity.ImportErrors{{Message: message}}),
+		})
+	return result.RowsAffected, result.Error
+}
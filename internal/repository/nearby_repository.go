@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+// distanceSQL is the haversine distance in kilometers from (@lat, @lng) to
+// the latitude and longitude columns of the row being matched
+const distanceSQL = `2 * 6371.0088 * ASIN(LEAST(1, SQRT(
+            POWER(SIN(RADIANS(latitude - @lat) / 2), 2)
+            + COS(RADIANS(@lat)) * COS(RADIANS(latitude)) * POWER(SIN(RADIANS(longitude - @lng) / 2), 2))))`
+
+// inBoundsSQL matches rows inside the bounding box, which may cross the
+// antimeridian. It lets the (latitude, longitude) indexes narrow candidates
+// before any distance is computed.
+const inBoundsSQL = `latitude BETWEEN @min_lat AND @max_lat
+      AND CASE WHEN CAST(@min_lng AS float8) <= CAST(@max_lng AS float8)
+               THEN longitude BETWEEN @min_lng AND @max_lng
+               ELSE longitude >= @min_lng OR longitude <= @max_lng END`
+
+// nearbyBooksSQL selects the available books of other active users inside
+// the box and, when @radius is positive, within @radius kilometers
+const nearbyBooksSQL = `
+WITH hidden AS (` + HiddenUsersSQL + `), candidates AS (
+    SELECT book_id, title, author, condition::text AS condition, COALESCE(image_url, '') AS image_url,
+           owner_id, latitude, longitude, ` + distanceSQL + ` AS distance_km
+    FROM books
+    WHERE is_available AND ` + inBoundsSQL + `
+), nearby AS (
+    SELECT c.*, u.username AS owner
+    FROM candidates c
+    JOIN users u ON u.user_id = c.owner_id
+    WHERE u.is_active AND c.owner_id <> @viewer
+      AND c.owner_id NOT IN (SELECT user_id FROM hidden)
+      AND (CAST(@radius AS float8) <= 0 OR c.distance_km <= @radius)
+)
+`
+
+// nearbyPostsSQL is nearbyBooksSQL for published posts
+const nearbyPostsSQL = `
+WITH hidden AS (` + HiddenUsersSQL + `), candidates AS (
+    SELECT post_id, type::text AS type, title, COALESCE(summary, '') AS summary, book_id,
+           user_id, latitude, longitude, ` + distanceSQL + ` AS distance_km
+    FROM posts
+    WHERE is_published AND ` + inBoundsSQL + `
+), nearby AS (
+    SELECT c.*, u.username AS author
+    FROM candidates c
+    JOIN users u ON u.user_id = c.user_id
+    WHERE u.is_active AND c.user_id <> @viewer
+      AND c.user_id NOT IN (SELECT user_id FROM hidden)
+      AND (CAST(@radius AS float8) <= 0 OR c.distance_km <= @radius)
+)
+`
+
+// NearbyFilter describes a location search. Results are inside Bounds and,
+// when RadiusKm is positive, within RadiusKm of Center. Content of ViewerID
+// and of users blocked either way is left out.
+type NearbyFilter struct {
+	ViewerID int
+	Center   entity.Coordinates
+	Bounds   entity.BoundingBox
+	RadiusKm float64
+	Limit    int
+	Offset   int
+}
+
+// NearbyRepository defines location searches over listings
+type NearbyRepository interface {
+	NearbyBooks(filter NearbyFilter) ([]entity.NearbyBook, int64, error)
+	NearbyPosts(filter NearbyFilter) ([]entity.NearbyPost, int64, error)
+}
+
+// GormNearbyRepository is a GORM implementation of NearbyRepository
+type GormNearbyRepository struct {
+	db *gorm.DB
+}
+
+// NewNearbyRepository creates a new GormNearbyRepository
+func NewNearbyRepository(db *gorm.DB) NearbyRepository {
+	return &GormNearbyRepository{db: db}
+}
+
+// NearbyBooks returns a page of matching books, closest first
+func (repo *GormNearbyRepository) NearbyBooks(filter NearbyFilter) ([]entity.NearbyBook, int64, error) {
+	books := []entity.NearbyBook{}
+	total, err := repo.search(nearbyBooksSQL, "book_id", filter, &books)
+	return books, total, err
+}
+
+// NearbyPosts returns a page of matching posts, closest first
+func (repo *GormNearbyRepository) NearbyPosts(filter NearbyFilter) ([]entity.NearbyPost, int64, error) {
+	posts := []entity.NearbyPost{}
+	total, err := repo.search(nearbyPostsSQL, "post_id", filter, &posts)
+	return posts, total, err
+}
+
+// search counts the rows of the nearby CTE in query and scans one page of
+// them into dest, sorted by distance and then idColumn
+func (repo *GormNearbyRepository) search(query, idColumn string, filter NearbyFilter, dest interface{}) (int64, error) {
+	args := map[string]interface{}{
+		"viewer":  filter.ViewerID,
+		"lat":     filter.Center.Latitude,
+		"lng":     filter.Center.Longitude,
+		"min_lat": filter.Bounds.MinLatitude,
+		"min_lng": filter.Bounds.MinLongitude,
+		"max_lat": filter.Bounds.MaxLatitude,
+		"max_lng": filter.Bounds.MaxLongitude,
+		"radius":  filter.RadiusKm,
+		"limit":   filter.Limit,
+		"offset":  filter.Offset,
+	}
+
+	var total int64
+	if err := repo.db.Raw(query+"SELECT COUNT(*) FROM nearby", args).Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	err := repo.db.Raw(query+"SELECT * FROM nearby ORDER BY distance_km, "+idColumn+" LIMIT @limit OFFSET @offset", args).Scan(dest).Error
+	return total, err
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+)
+
+// exportQueries select everything stored about a user outside the users
+// table, keyed by the section name used in exports. @id is the user's ID.
+// Secrets such as token and key hashes are left out.
+var exportQueries = []struct {
+	section string
+	query   string
+}{
+	{"roles", `SELECT r.name, ur.granted_at FROM user_roles ur JOIN roles r ON r.role_id = ur.role_id WHERE ur.user_id = @id`},
+	{"books", `SELECT * FROM books WHERE owner_id = @id ORDER BY book_id`},
+	{"book_condition_changes", `SELECT c.* FROM book_condition_changes c JOIN books b ON b.book_id = c.book_id WHERE b.owner_id = @id ORDER BY c.change_id`},
+	{"book_imports", `SELECT id, format, status, total_rows, processed_rows, imported, skipped, failed, errors, created_at, finished_at FROM import_jobs WHERE user_id = @id ORDER BY created_at`},
+	{"exchanges", `SELECT * FROM exchanges WHERE requester_id = @id OR book_id IN (SELECT book_id FROM books WHERE owner_id = @id) ORDER BY exchange_id`},
+	{"messages", `SELECT * FROM messages WHERE sender_id = @id OR receiver_id = @id ORDER BY message_id`},
+	{"reviews_written", `SELECT * FROM reviews WHERE reviewer_id = @id ORDER BY review_id`},
+	{"reviews_received", `SELECT * FROM reviews WHERE reviewed_user_id = @id ORDER BY review_id`},
+	{"wishlists", `SELECT * FROM wishlists WHERE user_id = @id ORDER BY wishlist_id`},
+	{"posts", `SELECT * FROM posts WHERE user_id = @id ORDER BY post_id`},
+	{"post_likes", `SELECT * FROM post_likes WHERE user_id = @id`},
+	{"post_saves", `SELECT * FROM post_saves WHERE user_id = @id`},
+	{"comments", `SELECT * FROM comments WHERE user_id = @id ORDER BY comment_id`},
+	{"comment_reactions", `SELECT * FROM comment_reactions WHERE user_id = @id`},
+	{"following", `SELECT u.username, f.created_at FROM follows f JOIN users u ON u.user_id = f.followee_id WHERE f.follower_id = @id ORDER BY f.created_at`},
+	{"followers", `SELECT u.username, f.created_at FROM follows f JOIN users u ON u.user_id = f.follower_id WHERE f.followee_id = @id ORDER BY f.created_at`},
+	{"blocked_users", `SELECT u.username, b.created_at FROM user_blocks b JOIN users u ON u.user_id = b.blocked_id WHERE b.blocker_id = @id ORDER BY b.created_at`},
+	{"sessions", `SELECT id, user_agent, ip_address, created_at, last_seen_at, revoked_at FROM sessions WHERE user_id = @id ORDER BY created_at`},
+	{"api_keys", `SELECT id, name, prefix, scopes, expires_at, last_used_at, created_at, revoked_at FROM api_keys WHERE user_id = @id ORDER BY created_at`},
+	{"external_identities", `SELECT provider, subject, email, created_at, last_login_at FROM external_identities WHERE user_id = @id`},
+	{"audit_logs", `SELECT action, ip_address, details, created_at FROM audit_logs WHERE user_id = @id ORDER BY created_at`},
+}
+
+// PrivacyRepository collects and erases personal data across all tables
+type PrivacyRepository interface {
+	CollectUserData(userID int) (map[string][]map[string]interface{}, error)
+	AnonymizeAndDelete(userID int) error
+}
+
+// GormPrivacyRepository is a GORM implementation of PrivacyRepository
+type GormPrivacyRepository struct {
+	db *gorm.DB
+}
+
+// NewPrivacyRepository creates a new GormPrivacyRepository
+func NewPrivacyRepository(db *gorm.DB) PrivacyRepository {
+	return &GormPrivacyRepository{db: db}
+}
+
+// CollectUserData returns the rows of every export section
+func (repo *GormPrivacyRepository) CollectUserData(userID int) (map[string][]map[string]interface{}, error) {
+	args := map[string]interface{}{"id": userID}
+	data := make(map[string][]map[string]interface{}, len(exportQueries))
+	for _, q := range exportQueries {
+		rows := []map[string]interface{}{}
+		if err := repo.db.Raw(q.query, args).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		data[q.section] = rows
+	}
+	return data, nil
+}
+
+// AnonymizeAndDelete deletes a user. Rows other users still need (messages
+// they received, reviews they got, exchanges they took part in) are kept but
+// detached from the user; everything else is removed by ON DELETE CASCADE.
+func (repo *GormPrivacyRepository) AnonymizeAndDelete(userID int) error {
+	args := map[string]interface{}{"id": userID}
+	statements := []string{
+		// Open exchanges cannot complete without the user
+		`UPDATE exchanges SET status = 'Cancelled'
+			WHERE status IN ('Pending', 'Accepted')
+			AND (requester_id = @id OR book_id IN (SELECT book_id FROM books WHERE owner_id = @id))`,
+		`UPDATE exchanges SET requester_id = NULL WHERE requester_id = @id`,
+		// The user's books are deleted with the account
+		`UPDATE exchanges SET book_id = NULL WHERE book_id IN (SELECT book_id FROM books WHERE owner_id = @id)`,
+		`UPDATE posts SET book_id = NULL WHERE book_id IN (SELECT book_id FROM books WHERE owner_id = @id)`,
+		`UPDATE messages SET sender_id = NULL WHERE sender_id = @id`,
+		`UPDATE messages SET receiver_id = NULL WHERE receiver_id = @id`,
+		`UPDATE reviews SET reviewer_id = NULL WHERE reviewer_id = @id`,
+		// Replies reference their parent without ON DELETE, so comments with
+		// replies are emptied and detached instead, keeping the threads intact
+		`UPDATE comments SET user_id = NULL, content = '', is_deleted = true
+			WHERE user_id = @id
+			AND comment_id IN (SELECT parent_comment_id FROM comments WHERE parent_comment_id IS NOT NULL)`,
+		// Reviews about a person who no longer exists serve no one
+		`DELETE FROM reviews WHERE reviewed_user_id = @id`,
+		`DELETE FROM users WHERE user_id = @id`,
+	}
+
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement, args).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
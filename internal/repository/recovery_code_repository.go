@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+var (
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found or already used")
+)
+
+// RecoveryCodeRepository defines methods for two-factor recovery code persistence
+type RecoveryCodeRepository interface {
+	ReplaceForUser(userID int, codeHashes []string) error
+	Consume(userID int, codeHash string) error
+	DeleteForUser(userID int) error
+}
+
+// GormRecoveryCodeRepository is a GORM implementation of RecoveryCodeRepository
+type GormRecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewRecoveryCodeRepository creates a new GormRecoveryCodeRepository
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &GormRecoveryCodeRepository{db: db}
+}
+
+// ReplaceForUser discards a user's previous codes and stores a new set
+func (repo *GormRecoveryCodeRepository) ReplaceForUser(userID int, codeHashes []string) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]entity.RecoveryCode, len(codeHashes))
+		for i, hash := range codeHashes {
+			codes[i] = entity.RecoveryCode{UserID: userID, CodeHash: hash}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// Consume marks an unused code as used
+func (repo *GormRecoveryCodeRepository) Consume(userID int, codeHash string) error {
+	result := repo.db.Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+// DeleteForUser removes all of a user's codes
+func (repo *GormRecoveryCodeRepository) DeleteForUser(userID int) error {
+	return repo.db.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenReused   = errors.New("refresh token already used")
+)
+
+// RefreshTokenRepository defines methods for refresh token persistence
+type RefreshTokenRepository interface {
+	Create(token *entity.RefreshToken) error
+	FindByHash(hash string) (*entity.RefreshToken, error)
+	Rotate(current *entity.RefreshToken, next *entity.RefreshToken) error
+	RevokeFamily(familyID uuid.UUID) error
+	RevokeAllForUser(userID int) error
+}
+
+// GormRefreshTokenRepository is a GORM implementation of RefreshTokenRepository
+type GormRefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository creates a new GormRefreshTokenRepository
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &GormRefreshTokenRepository{db: db}
+}
+
+// Create stores a new refresh token
+func (repo *GormRefreshTokenRepository) Create(token *entity.RefreshToken) error {
+	return repo.db.Create(token).Error
+}
+
+// FindByHash retrieves a refresh token by the hash of its value
+func (repo *GormRefreshTokenRepository) FindByHash(hash string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	if err := repo.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Rotate marks the current token as used and stores its successor in one
+// transaction. If another request consumed the current token first, the
+// conditional update matches no rows and ErrRefreshTokenReused is returned.
+func (repo *GormRefreshTokenRepository) Rotate(current *entity.RefreshToken, next *entity.RefreshToken) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", current.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+		return tx.Create(next).Error
+	})
+}
+
+// RevokeFamily revokes every token descended from the same login, and the
+// login's session
+func (repo *GormRefreshTokenRepository) RevokeFamily(familyID uuid.UUID) error {
+	now := time.Now()
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.Session{}).
+			Where("id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error
+	})
+}
+
+// RevokeAllForUser revokes every outstanding refresh token and session of a user
+func (repo *GormRefreshTokenRepository) RevokeAllForUser(userID int) error {
+	now := time.Now()
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+	})
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+// RevokedTokenRepository defines methods for the access token revocation list
+type RevokedTokenRepository interface {
+	Revoke(token *entity.RevokedToken) error
+	IsRevoked(jti string) (bool, error)
+	DeleteExpired() error
+}
+
+// GormRevokedTokenRepository is a GORM implementation of RevokedTokenRepository
+type GormRevokedTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRevokedTokenRepository creates a new GormRevokedTokenRepository
+func NewRevokedTokenRepository(db *gorm.DB) RevokedTokenRepository {
+	return &GormRevokedTokenRepository{db: db}
+}
+
+// Revoke adds a token to the revocation list; revoking twice is a no-op
+func (repo *GormRevokedTokenRepository) Revoke(token *entity.RevokedToken) error {
+	return repo.db.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+// IsRevoked reports whether the token with the given jti has been revoked
+func (repo *GormRevokedTokenRepository) IsRevoked(jti string) (bool, error) {
+	var count int64
+	if err := repo.db.Model(&entity.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteExpired removes entries for tokens that would be rejected as expired anyway
+func (repo *GormRevokedTokenRepository) DeleteExpired() error {
+	return repo.db.Where("expires_at < ?", time.Now()).Delete(&entity.RevokedToken{}).Error
+}
+
+// maxCheckedTokens caps the "not revoked" answers kept in memory; past it the
+// least recently used ones are dropped
+const maxCheckedTokens = 50000
+
+// CachedRevokedTokenRepository keeps revocation lookups off the database for
+// the hot path of every authenticated request. Revocations are cached until
+// the token expires; "not revoked" answers are only trusted for negativeTTL
+// so that revocations made by other instances are picked up quickly, and at
+// most maxCheckedTokens of them are kept.
+type CachedRevokedTokenRepository struct {
+	inner       RevokedTokenRepository
+	negativeTTL time.Duration
+	maxChecked  int
+
+	mu      sync.Mutex
+	revoked map[string]time.Time // jti -> token expiry
+	// checked holds negative answers, most recently used first
+	checked      *list.List
+	checkedIndex map[string]*list.Element
+}
+
+// checkedToken is a cached "not revoked" answer
+type checkedToken struct {
+	jti   string
+	until time.Time
+}
+
+// NewCachedRevokedTokenRepository wraps a RevokedTokenRepository with an in-memory cache
+func NewCachedRevokedTokenRepository(inner RevokedTokenRepository, negativeTTL time.Duration) *CachedRevokedTokenRepository {
+	return &CachedRevokedTokenRepository{
+		inner:        inner,
+		negativeTTL:  negativeTTL,
+		maxChecked:   maxCheckedTokens,
+		revoked:      make(map[string]time.Time),
+		checked:      list.New(),
+		checkedIndex: make(map[string]*list.Element),
+	}
+}
+
+// Revoke persists the revocation and records it in the cache
+func (c *CachedRevokedTokenRepository) Revoke(token *entity.RevokedToken) error {
+	if err := c.inner.Revoke(token); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.revoked[token.JTI] = token.ExpiresAt
+	c.forgetChecked(token.JTI)
+	c.mu.Unlock()
+	return nil
+}
+
+// IsRevoked answers from the cache when possible and falls back to the database
+func (c *CachedRevokedTokenRepository) IsRevoked(jti string) (bool, error) {
+	now := time.Now()
+	c.mu.Lock()
+	if _, revoked := c.revoked[jti]; revoked {
+		c.mu.Unlock()
+		return true, nil
+	}
+	if element, ok := c.checkedIndex[jti]; ok {
+		if now.Before(element.Value.(*checkedToken).until) {
+			c.checked.MoveToFront(element)
+			c.mu.Unlock()
+			return false, nil
+		}
+		c.forgetChecked(jti)
+	}
+	c.mu.Unlock()
+
+	isRevoked, err := c.inner.IsRevoked(jti)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	if isRevoked {
+		// The token expiry is not known here, so re-check the database later
+		c.revoked[jti] = now.Add(c.negativeTTL)
+	} else {
+		c.rememberChecked(jti, now.Add(c.negativeTTL))
+	}
+	c.mu.Unlock()
+	return isRevoked, nil
+}
+
+// rememberChecked caches a negative answer, evicting the least recently
+// used one when full. c.mu must be held.
+func (c *CachedRevokedTokenRepository) rememberChecked(jti string, until time.Time) {
+	if element, ok := c.checkedIndex[jti]; ok {
+		element.Value.(*checkedToken).until = until
+		c.checked.MoveToFront(element)
+		return
+	}
+	c.checkedIndex[jti] = c.checked.PushFront(&checkedToken{jti: jti, until: until})
+	for c.checked.Len() > c.maxChecked {
+		c.forgetChecked(c.checked.Back().Value.(*checkedToken).jti)
+	}
+}
+
+// forgetChecked drops a negative answer, if any. c.mu must be held.
+func (c *CachedRevokedTokenRepository) forgetChecked(jti string) {
+	if element, ok := c.checkedIndex[jti]; ok {
+		c.checked.Remove(element)
+		delete(c.checkedIndex, jti)
+	}
+}
+
+// DeleteExpired purges the database table and drops stale cache entries
+func (c *CachedRevokedTokenRepository) DeleteExpired() error {
+	now := time.Now()
+	c.mu.Lock()
+	for jti, expiresAt := range c.revoked {
+		if now.After(expiresAt) {
+			delete(c.revoked, jti)
+		}
+	}
+	for element := c.checked.Front(); element != nil; {
+		next := element.Next()
+		if entry := element.Value.(*checkedToken); now.After(entry.until) {
+			c.checked.Remove(element)
+			delete(c.checkedIndex, entry.jti)
+		}
+		element = next
+	}
+	c.mu.Unlock()
+	return c.inner.DeleteExpired()
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+var (
+	ErrRoleNotFound = errors.New("role not found")
+)
+
+// RoleRepository defines methods for role and permission persistence
+type RoleRepository interface {
+	ListRoles() ([]entity.Role, error)
+	RolesForUser(userID int) ([]string, error)
+	PermissionsForUser(userID int) ([]string, error)
+	AssignRole(userID int, role string, grantedBy *int) error
+	SetUserRoles(userID int, roles []string, grantedBy int) error
+}
+
+// GormRoleRepository is a GORM implementation of RoleRepository
+type GormRoleRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository creates a new GormRoleRepository
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &GormRoleRepository{db: db}
+}
+
+// ListRoles returns every role with its permissions
+func (repo *GormRoleRepository) ListRoles() ([]entity.Role, error) {
+	var roles []entity.Role
+	err := repo.db.Preload("Permissions").Order("name").Find(&roles).Error
+	return roles, err
+}
+
+// RolesForUser returns the names of the roles assigned to a user
+func (repo *GormRoleRepository) RolesForUser(userID int) ([]string, error) {
+	var names []string
+	err := repo.db.Table("user_roles").
+		Select("roles.name").
+		Joins("JOIN roles ON roles.role_id = user_roles.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Pluck("roles.name", &names).Error
+	return names, err
+}
+
+// PermissionsForUser returns the union of the permissions of a user's roles
+func (repo *GormRoleRepository) PermissionsForUser(userID int) ([]string, error) {
+	var names []string
+	err := repo.db.Table("user_roles").
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.role_id = user_roles.role_id").
+		Joins("JOIN permissions ON permissions.permission_id = role_permissions.permission_id").
+		Where("user_roles.user_id = ?", userID).
+		Order("permissions.name").
+		Pluck("permissions.name", &names).Error
+	return names, err
+}
+
+// AssignRole grants a single role to a user; granting it twice is a no-op
+func (repo *GormRoleRepository) AssignRole(userID int, role string, grantedBy *int) error {
+	return assignRole(repo.db, userID, role, grantedBy)
+}
+
+// assignRole grants a role within db, which may be a transaction
+func assignRole(db *gorm.DB, userID int, role string, grantedBy *int) error {
+	var found entity.Role
+	if err := db.Where("name = ?", role).First(&found).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRoleNotFound
+		}
+		return err
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&entity.UserRole{UserID: userID, RoleID: found.ID, GrantedBy: grantedBy}).Error
+}
+
+// SetUserRoles replaces all roles of a user. Roles named twice are granted once.
+func (repo *GormRoleRepository) SetUserRoles(userID int, roles []string, grantedBy int) error {
+	roles = uniqueStrings(roles)
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var found []entity.Role
+		if err := tx.Where("name IN ?", roles).Find(&found).Error; err != nil {
+			return err
+		}
+		if len(found) != len(roles) {
+			return ErrRoleNotFound
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.UserRole{}).Error; err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return nil
+		}
+		assignments := make([]entity.UserRole, len(found))
+		for i, role := range found {
+			assignments[i] = entity.UserRole{UserID: userID, RoleID: role.ID, GrantedBy: &grantedBy}
+		}
+		return tx.Create(&assignments).Error
+	})
+}
+
+// uniqueStrings returns values without repeats, keeping their order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
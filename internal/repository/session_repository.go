@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// lastSeenResolution limits how often last_seen_at is written for a busy session
+const lastSeenResolution = time.Minute
+
+// SessionRepository defines methods for login session persistence. Sessions
+// are revoked through RefreshTokenRepository together with their tokens.
+type SessionRepository interface {
+	Create(session *entity.Session) error
+	FindByID(id uuid.UUID) (*entity.Session, error)
+	ListActiveForUser(userID int, since time.Time) ([]entity.Session, error)
+	TouchLastSeen(id uuid.UUID, ip string, at time.Time) error
+}
+
+// GormSessionRepository is a GORM implementation of SessionRepository
+type GormSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new GormSessionRepository
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &GormSessionRepository{db: db}
+}
+
+// Create stores a new session
+func (repo *GormSessionRepository) Create(session *entity.Session) error {
+	return repo.db.Create(session).Error
+}
+
+// FindByID retrieves a session, revoked or not
+func (repo *GormSessionRepository) FindByID(id uuid.UUID) (*entity.Session, error) {
+	var session entity.Session
+	if err := repo.db.Where("id = ?", id).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListActiveForUser returns a user's unrevoked sessions seen after since,
+// most recently used first
+func (repo *GormSessionRepository) ListActiveForUser(userID int, since time.Time) ([]entity.Session, error) {
+	var sessions []entity.Session
+	err := repo.db.Where("user_id = ? AND revoked_at IS NULL AND last_seen_at > ?", userID, since).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// TouchLastSeen records activity on a session, at most once per lastSeenResolution
+func (repo *GormSessionRepository) TouchLastSeen(id uuid.UUID, ip string, at time.Time) error {
+	return repo.db.Model(&entity.Session{}).
+		Where("id = ? AND last_seen_at < ?", id, at.Add(-lastSeenResolution)).
+		Updates(map[string]interface{}{"last_seen_at": at, "ip_address": ip}).Error
+}
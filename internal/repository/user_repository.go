@@ -2,7 +2,9 @@ package repository
 
 import (
 	"errors"
-	"github.com/almatkai/book-exchange-backend/pkg/utils"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 
 	"github.com/almatkai/book-exchange-backend/internal/entity"
@@ -13,14 +15,41 @@ var (
 	ErrUsernameExists     = errors.New("username already exists")
 	ErrEmailExists        = errors.New("email already exists")
 	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrTOTPCodeReused     = errors.New("totp code already used")
+	ErrNotSuspended       = errors.New("account is not suspended")
+	// ErrLoginAttemptConflict means another login attempt on the account
+	// was counted in the meantime
+	ErrLoginAttemptConflict = errors.New("another login attempt is in progress")
 )
 
 // UserRepository defines methods for user data persistence
 type UserRepository interface {
-	Create(user *entity.User) error
+	Create(user *entity.User, role string) error
 	FindByUsername(username string) (*entity.User, error)
 	FindByEmail(email string) (*entity.User, error)
+	FindByLogin(login string) (*entity.User, error)
+	FindByID(userID int) (*entity.User, error)
 	UpdateLastLogin(userID int) error
+	IncrementTokenVersion(userID int) error
+	UpdatePassword(userID int, passwordHash string) error
+	MarkEmailVerified(userID int) error
+	SetTOTPSecret(userID int, secret string) error
+	EnableTOTP(userID int) error
+	DisableTOTP(userID int) error
+	RecordTOTPStep(userID int, step int64) error
+	ReserveLoginAttempt(userID, failures int, now time.Time) (int, error)
+	ResetFailedLogins(userID int) error
+	LockAccount(userID int, until time.Time) error
+	List(limit, offset int) ([]entity.User, int64, error)
+	Suspend(userID int, at time.Time) error
+	Unsuspend(userID int) error
+	FindByLoginIncludingInactive(login string) (*entity.User, error)
+	Deactivate(userID int, at time.Time, deleteAt *time.Time) error
+	Reactivate(userID int) error
+	ListDueForDeletion(now time.Time, limit int) ([]int, error)
+	UpdateProfile(userID int, update entity.UpdateProfileRequest) error
+	SetAvatar(userID int, avatarID *string, urls entity.AvatarURLs) error
+	SetLocation(userID int, exact, approx *entity.Coordinates) error
 }
 
 // GormUserRepository is a GORM implementation of UserRepository
@@ -33,41 +62,57 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &GormUserRepository{db: db}
 }
 
-// Create inserts a new user into the database
-func (repo *GormUserRepository) Create(user *entity.User) error {
-	// Check if username already exists
-	var existingUser entity.User
-	if err := repo.db.Where("username = ?", user.Username).First(&existingUser).Error; err == nil {
-		return ErrUsernameExists
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return err
-	}
+// Create inserts a new user into the database along with their first role.
+// user.Password must already be hashed. Usernames and emails are unique
+// regardless of case.
+func (repo *GormUserRepository) Create(user *entity.User, role string) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		// Check if username already exists
+		var existingUser entity.User
+		if err := tx.Where("LOWER(username) = LOWER(?)", user.Username).First(&existingUser).Error; err == nil {
+			return ErrUsernameExists
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
 
-	// Check if email already exists
-	if err := repo.db.Where("email = ?", user.Email).First(&existingUser).Error; err == nil {
-		return ErrEmailExists
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return err
-	}
+		// Check if email already exists
+		if err := tx.Where("LOWER(email) = LOWER(?)", user.Email).First(&existingUser).Error; err == nil {
+			return ErrEmailExists
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
 
-	hashedPassword, err := utils.HashPassword(user.Password)
-	if err != nil {
-		return err
-	}
-	user.Password = hashedPassword
+		// Create the user; without a role the account could not do anything.
+		// A concurrent registration can still take the name or email after
+		// the checks above, which the unique indexes catch.
+		if err := tx.Create(user).Error; err != nil {
+			return identifierTaken(err)
+		}
+		return assignRole(tx, user.UserID, role, nil)
+	})
+}
 
-	// Create the user
-	if err := repo.db.Create(user).Error; err != nil {
+// identifierTaken maps violations of the unique indexes on usernames and
+// emails to ErrUsernameExists and ErrEmailExists, and returns other errors
+// unchanged
+func identifierTaken(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
 		return err
 	}
-
-	return nil
+	switch pgErr.ConstraintName {
+	case "idx_users_username_lower", "users_username_key":
+		return ErrUsernameExists
+	case "idx_users_email_lower", "users_email_key":
+		return ErrEmailExists
+	}
+	return err
 }
 
-// FindByUsername retrieves a user by username
+// FindByUsername retrieves an active user by username, ignoring case
 func (repo *GormUserRepository) FindByUsername(username string) (*entity.User, error) {
 	var user entity.User
-	if err := repo.db.Where("username = ? AND is_active = ?", username, true).First(&user).Error; err != nil {
+	if err := repo.db.Where("LOWER(username) = LOWER(?) AND is_active = ?", username, true).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrUserNotFound
 		}
@@ -76,10 +121,27 @@ func (repo *GormUserRepository) FindByUsername(username string) (*entity.User, e
 	return &user, nil
 }
 
-// FindByEmail retrieves a user by email
+// FindByEmail retrieves an active user by email, ignoring case
 func (repo *GormUserRepository) FindByEmail(email string) (*entity.User, error) {
 	var user entity.User
-	if err := repo.db.Where("email = ? AND is_active = ?", email, true).First(&user).Error; err != nil {
+	if err := repo.db.Where("LOWER(email) = LOWER(?) AND is_active = ?", email, true).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindByLogin retrieves an active user by username or email, ignoring case
+func (repo *GormUserRepository) FindByLogin(login string) (*entity.User, error) {
+	return repo.findByLogin(repo.db.Where("is_active = ?", true), login)
+}
+
+// FindByID retrieves a user by primary key, including inactive accounts
+func (repo *GormUserRepository) FindByID(userID int) (*entity.User, error) {
+	var user entity.User
+	if err := repo.db.Where("user_id = ?", userID).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrUserNotFound
 		}
@@ -92,3 +154,250 @@ func (repo *GormUserRepository) FindByEmail(email string) (*entity.User, error)
 func (repo *GormUserRepository) UpdateLastLogin(userID int) error {
 	return repo.db.Model(&entity.User{}).Where("user_id = ?", userID).Update("last_login", gorm.Expr("NOW()")).Error
 }
+
+// IncrementTokenVersion invalidates every access token issued to a user so far
+func (repo *GormUserRepository) IncrementTokenVersion(userID int) error {
+	return repo.db.Model(&entity.User{}).Where("user_id = ?", userID).Update("token_version", gorm.Expr("token_version + 1")).Error
+}
+
+// UpdatePassword stores a new, already hashed, password for a user
+func (repo *GormUserRepository) UpdatePassword(userID int, passwordHash string) error {
+	return repo.db.Model(&entity.User{}).Where("user_id = ?", userID).Update("password_hash", passwordHash).Error
+}
+
+// MarkEmailVerified records that the user confirmed their email address
+func (repo *GormUserRepository) MarkEmailVerified(userID int) error {
+	return repo.db.Model(&entity.User{}).
+		Where("user_id = ? AND email_verified_at IS NULL", userID).
+		Update("email_verified_at", gorm.Expr("NOW()")).Error
+}
+
+// SetTOTPSecret stores a new, not yet confirmed, TOTP secret
+func (repo *GormUserRepository) SetTOTPSecret(userID int, secret string) error {
+	return repo.db.Model(&entity.User{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":     secret,
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error
+}
+
+// EnableTOTP starts enforcing the stored TOTP secret on login
+func (repo *GormUserRepository) EnableTOTP(userID int) error {
+	return repo.db.Model(&entity.User{}).Where("user_id = ?", userID).Update("totp_enabled_at", gorm.Expr("NOW()")).Error
+}
+
+// DisableTOTP removes the TOTP secret
+func (repo *GormUserRepository) DisableTOTP(userID int) error {
+	return repo.db.Model(&entity.User{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":     nil,
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error
+}
+
+// RecordTOTPStep remembers the last accepted TOTP time step. A step that is
+// not newer than the stored one is rejected with ErrTOTPCodeReused.
+func (repo *GormUserRepository) RecordTOTPStep(userID int, step int64) error {
+	result := repo.db.Model(&entity.User{}).
+		Where("user_id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTOTPCodeReused
+	}
+	return nil
+}
+
+// ReserveLoginAttempt counts an attempt as failed before its password or code
+// is checked, and returns the new failure count. It only does so while the
+// account is unlocked and its count is still failures, the value the caller
+// checked the backoff against; otherwise a concurrent attempt got there first
+// and it returns ErrLoginAttemptConflict.
+func (repo *GormUserRepository) ReserveLoginAttempt(userID, failures int, now time.Time) (int, error) {
+	var count int
+	result := repo.db.Raw(
+		`UPDATE users SET failed_login_count = failed_login_count + 1, last_failed_login_at = ?
+		WHERE user_id = ? AND failed_login_count = ? AND (locked_until IS NULL OR locked_until <= ?)
+		RETURNING failed_login_count`,
+		now, userID, failures, now,
+	).Scan(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, ErrLoginAttemptConflict
+	}
+	return count, nil
+}
+
+// ResetFailedLogins clears the failed login counter and any lock
+func (repo *GormUserRepository) ResetFailedLogins(userID int) error {
+	return repo.db.Model(&entity.User{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"failed_login_count":   0,
+		"last_failed_login_at": nil,
+		"locked_until":         nil,
+	}).Error
+}
+
+// LockAccount refuses logins to an account until the given time
+func (repo *GormUserRepository) LockAccount(userID int, until time.Time) error {
+	return repo.db.Model(&entity.User{}).Where("user_id = ?", userID).Update("locked_until", until).Error
+}
+
+// List returns a page of users ordered by id, with the total number of users
+func (repo *GormUserRepository) List(limit, offset int) ([]entity.User, int64, error) {
+	var users []entity.User
+	var total int64
+	if err := repo.db.Model(&entity.User{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := repo.db.Order("user_id").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// Suspend disables an account on a moderator's behalf
+func (repo *GormUserRepository) Suspend(userID int, at time.Time) error {
+	result := repo.db.Model(&entity.User{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"is_active":    false,
+		"suspended_at": gorm.Expr("COALESCE(suspended_at, ?)", at),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Unsuspend lifts a suspension. An account its owner had deactivated before
+// stays deactivated, so the owner can still reactivate or delete it.
+func (repo *GormUserRepository) Unsuspend(userID int) error {
+	result := repo.db.Model(&entity.User{}).
+		Where("user_id = ? AND suspended_at IS NOT NULL", userID).
+		Updates(map[string]interface{}{
+			"is_active":    gorm.Expr("deactivated_at IS NULL"),
+			"suspended_at": nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotSuspended
+	}
+	return nil
+}
+
+// FindByLoginIncludingInactive retrieves a user by username or email,
+// ignoring case, whether or not the account is active
+func (repo *GormUserRepository) FindByLoginIncludingInactive(login string) (*entity.User, error) {
+	return repo.findByLogin(repo.db, login)
+}
+
+// findByLogin matches login against usernames, then emails. Usernames cannot
+// contain "@", but older ones might, so a username match wins.
+func (repo *GormUserRepository) findByLogin(query *gorm.DB, login string) (*entity.User, error) {
+	for _, column := range []string{"username", "email"} {
+		var user entity.User
+		err := query.Session(&gorm.Session{}).Where("LOWER("+column+") = LOWER(?)", login).First(&user).Error
+		if err == nil {
+			return &user, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// Deactivate disables an account at the owner's request, optionally
+// scheduling its deletion
+func (repo *GormUserRepository) Deactivate(userID int, at time.Time, deleteAt *time.Time) error {
+	return repo.db.Model(&entity.User{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"is_active":             false,
+		"deactivated_at":        gorm.Expr("COALESCE(deactivated_at, ?)", at),
+		"deletion_scheduled_at": deleteAt,
+	}).Error
+}
+
+// Reactivate re-enables a self-deactivated account and cancels a pending
+// deletion. Suspended accounts are left alone.
+func (repo *GormUserRepository) Reactivate(userID int) error {
+	result := repo.db.Model(&entity.User{}).
+		Where("user_id = ? AND deactivated_at IS NOT NULL AND suspended_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"is_active":             true,
+			"deactivated_at":        nil,
+			"deletion_scheduled_at": nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ListDueForDeletion returns IDs of accounts whose deletion grace period has ended
+func (repo *GormUserRepository) ListDueForDeletion(now time.Time, limit int) ([]int, error) {
+	var ids []int
+	err := repo.db.Model(&entity.User{}).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ? AND is_active = ?", now, false).
+		Order("deletion_scheduled_at").
+		Limit(limit).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// UpdateProfile stores the profile fields set in update
+func (repo *GormUserRepository) UpdateProfile(userID int, update entity.UpdateProfileRequest) error {
+	changes := map[string]interface{}{}
+	if update.FullName != nil {
+		changes["full_name"] = *update.FullName
+	}
+	if update.Location != nil {
+		changes["location"] = *update.Location
+	}
+	if update.Bio != nil {
+		changes["bio"] = *update.Bio
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return repo.db.Model(&entity.User{}).Where("user_id = ?", userID).Updates(changes).Error
+}
+
+// SetAvatar stores the current avatar; a nil avatarID removes it
+func (repo *GormUserRepository) SetAvatar(userID int, avatarID *string, urls entity.AvatarURLs) error {
+	return repo.db.Model(&entity.User{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"avatar_id":   avatarID,
+		"avatar_urls": urls,
+	}).Error
+}
+
+// SetLocation stores the user's exact position and moves their books and
+// posts to the approximate one; nil clears both
+func (repo *GormUserRepository) SetLocation(userID int, exact, approx *entity.Coordinates) error {
+	var lat, lng, approxLat, approxLng *float64
+	if exact != nil && approx != nil {
+		lat, lng = &exact.Latitude, &exact.Longitude
+		approxLat, approxLng = &approx.Latitude, &approx.Longitude
+	}
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.User{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"latitude":  lat,
+			"longitude": lng,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE books SET latitude = ?, longitude = ? WHERE owner_id = ?", approxLat, approxLng, userID).Error; err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE posts SET latitude = ?, longitude = ? WHERE user_id = ?", approxLat, approxLng, userID).Error
+	})
+}
@@ -0,0 +1,274 @@
+// internal/usecase/account_usecase.go
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+	"github.com/almatkai/book-exchange-backend/pkg/mailer"
+	"github.com/almatkai/book-exchange-backend/pkg/utils"
+)
+
+var (
+	ErrInvalidResetToken        = errors.New("invalid or expired reset token")
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrEmailAlreadyVerified     = errors.New("email already verified")
+	ErrTooManyRequests          = errors.New("too many requests, try again later")
+	ErrInvalidUnlockToken       = errors.New("invalid or expired unlock token")
+)
+
+// AccountUseCase covers account recovery and verification flows driven by
+// tokens sent to the user by email
+type AccountUseCase interface {
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) error
+	SendEmailVerification(user *entity.User) error
+	ResendEmailVerification(user *entity.User) error
+	VerifyEmail(token string) error
+	SendAccountUnlock(user *entity.User) error
+	UnlockAccount(token string) error
+}
+
+// AccountSettings tunes token lifetimes and throttling of account emails
+type AccountSettings struct {
+	AppBaseURL           string
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+	AccountUnlockTTL     time.Duration
+	// A verification email can be resent once per ResendCooldown and at most
+	// ResendDailyLimit times per 24 hours
+	ResendCooldown   time.Duration
+	ResendDailyLimit int
+}
+
+type accountUseCase struct {
+	userRepo    repository.UserRepository
+	refreshRepo repository.RefreshTokenRepository
+	tokenRepo   repository.ActionTokenRepository
+	auditRepo   repository.AuditRepository
+	mailer      mailer.Mailer
+	hasher      utils.PasswordHasher
+	settings    AccountSettings
+}
+
+func NewAccountUseCase(userRepo repository.UserRepository, refreshRepo repository.RefreshTokenRepository, tokenRepo repository.ActionTokenRepository, auditRepo repository.AuditRepository, m mailer.Mailer, hasher utils.PasswordHasher, settings AccountSettings) AccountUseCase {
+	return &accountUseCase{
+		userRepo:    userRepo,
+		refreshRepo: refreshRepo,
+		tokenRepo:   tokenRepo,
+		auditRepo:   auditRepo,
+		mailer:      m,
+		hasher:      hasher,
+		settings:    settings,
+	}
+}
+
+// RequestPasswordReset emails a reset link to the account with the given
+// email. Unknown addresses are silently ignored so the endpoint cannot be used
+// to discover which emails are registered.
+func (uc *accountUseCase) RequestPasswordReset(email string) error {
+	user, err := uc.userRepo.FindByEmail(email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// Only the most recently requested link stays valid
+	if err := uc.tokenRepo.InvalidateForUser(user.UserID, entity.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+	token, err := uc.issueActionToken(user.UserID, entity.TokenPurposePasswordReset, uc.settings.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Book Exchange password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your Book Exchange account. "+
+			"If it was you, open the link below within %s:\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n",
+			user.Username, uc.settings.PasswordResetTTL, uc.link("/password/reset", token)),
+	}
+	if err := uc.mailer.Send(msg); err != nil {
+		// Don't reveal delivery problems to the caller
+		log.Printf("failed to send password reset email to user %d: %v", user.UserID, err)
+	}
+	return nil
+}
+
+// ResetPassword redeems a reset token, sets the new password and ends every
+// existing session of the account
+func (uc *accountUseCase) ResetPassword(token, newPassword string) error {
+	record, err := uc.redeemActionToken(token, entity.TokenPurposePasswordReset)
+	if err != nil {
+		if errors.Is(err, repository.ErrActionTokenNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user, err := uc.userRepo.FindByID(record.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	hash, err := uc.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := uc.userRepo.UpdatePassword(user.UserID, hash); err != nil {
+		return err
+	}
+	if err := uc.tokenRepo.InvalidateForUser(user.UserID, entity.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+
+	// Whoever knew the old password must not stay logged in
+	if err := uc.userRepo.IncrementTokenVersion(user.UserID); err != nil {
+		return err
+	}
+	return uc.refreshRepo.RevokeAllForUser(user.UserID)
+}
+
+// SendEmailVerification emails a verification link to a newly registered user
+func (uc *accountUseCase) SendEmailVerification(user *entity.User) error {
+	if user.EmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	if err := uc.tokenRepo.InvalidateForUser(user.UserID, entity.TokenPurposeEmailVerification); err != nil {
+		return err
+	}
+	token, err := uc.issueActionToken(user.UserID, entity.TokenPurposeEmailVerification, uc.settings.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return uc.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your Book Exchange email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below within %s:\n\n%s\n",
+			user.Username, uc.settings.EmailVerificationTTL, uc.link("/verify-email", token)),
+	})
+}
+
+// ResendEmailVerification sends a fresh verification link, subject to throttling
+func (uc *accountUseCase) ResendEmailVerification(user *entity.User) error {
+	if user.EmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	now := time.Now()
+	recent, err := uc.tokenRepo.CountIssuedSince(user.UserID, entity.TokenPurposeEmailVerification, now.Add(-uc.settings.ResendCooldown))
+	if err != nil {
+		return err
+	}
+	if recent > 0 {
+		return ErrTooManyRequests
+	}
+	today, err := uc.tokenRepo.CountIssuedSince(user.UserID, entity.TokenPurposeEmailVerification, now.Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if today >= int64(uc.settings.ResendDailyLimit) {
+		return ErrTooManyRequests
+	}
+
+	return uc.SendEmailVerification(user)
+}
+
+// VerifyEmail redeems a verification token and marks the email as verified
+func (uc *accountUseCase) VerifyEmail(token string) error {
+	record, err := uc.redeemActionToken(token, entity.TokenPurposeEmailVerification)
+	if err != nil {
+		if errors.Is(err, repository.ErrActionTokenNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+	return uc.userRepo.MarkEmailVerified(record.UserID)
+}
+
+// SendAccountUnlock emails a link that lifts a lockout caused by failed logins
+func (uc *accountUseCase) SendAccountUnlock(user *entity.User) error {
+	if err := uc.tokenRepo.InvalidateForUser(user.UserID, entity.TokenPurposeAccountUnlock); err != nil {
+		return err
+	}
+	token, err := uc.issueActionToken(user.UserID, entity.TokenPurposeAccountUnlock, uc.settings.AccountUnlockTTL)
+	if err != nil {
+		return err
+	}
+
+	return uc.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your Book Exchange account has been locked",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account was temporarily locked after too many failed login attempts. "+
+			"If these attempts were yours, you can unlock it right away with the link below:\n\n%s\n\n"+
+			"If they were not, someone may be guessing your password. Consider resetting it.\n",
+			user.Username, uc.link("/account/unlock", token)),
+	})
+}
+
+// UnlockAccount redeems an unlock token and clears the lockout
+func (uc *accountUseCase) UnlockAccount(token string) error {
+	record, err := uc.redeemActionToken(token, entity.TokenPurposeAccountUnlock)
+	if err != nil {
+		if errors.Is(err, repository.ErrActionTokenNotFound) {
+			return ErrInvalidUnlockToken
+		}
+		return err
+	}
+
+	if err := uc.userRepo.ResetFailedLogins(record.UserID); err != nil {
+		return err
+	}
+	if err := uc.auditRepo.Record(&entity.AuditLog{UserID: &record.UserID, Action: entity.AuditAccountUnlocked, Details: "via email link"}); err != nil {
+		log.Printf("failed to write audit log %q: %v", entity.AuditAccountUnlocked, err)
+	}
+	return nil
+}
+
+// issueActionToken stores a new single-use token and returns its raw value
+func (uc *accountUseCase) issueActionToken(userID int, purpose string, ttl time.Duration) (string, error) {
+	raw, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	err = uc.tokenRepo.Create(&entity.ActionToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	return raw, err
+}
+
+// redeemActionToken validates and consumes a single-use token
+func (uc *accountUseCase) redeemActionToken(raw, purpose string) (*entity.ActionToken, error) {
+	record, err := uc.tokenRepo.FindUnused(utils.HashToken(raw), purpose)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, repository.ErrActionTokenNotFound
+	}
+	if err := uc.tokenRepo.Consume(record.ID); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (uc *accountUseCase) link(path, token string) string {
+	return uc.settings.AppBaseURL + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,201 @@
+// internal/usecase/admin_usecase.go
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+)
+
+var (
+	ErrUserNotFound      = repository.ErrUserNotFound
+	ErrRoleNotFound      = repository.ErrRoleNotFound
+	ErrNotSuspended      = repository.ErrNotSuspended
+	ErrCannotModifySelf  = errors.New("administrators cannot change their own roles or status")
+	ErrInsufficientRight = errors.New("insufficient rights for this operation")
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// AdminUseCase covers administration and moderation of accounts
+type AdminUseCase interface {
+	ListUsers(limit, offset int) (*entity.Page[entity.UserWithRoles], error)
+	ListRoles() ([]entity.Role, error)
+	SetUserRoles(actor *entity.User, userID int, roles []string, client entity.ClientInfo) error
+	SuspendUser(actor *entity.User, userID int, reason string, client entity.ClientInfo) error
+	UnsuspendUser(actor *entity.User, userID int, client entity.ClientInfo) error
+	ListAuditLogs(limit, offset int) (*entity.Page[entity.AuditLog], error)
+}
+
+type adminUseCase struct {
+	userRepo    repository.UserRepository
+	roleRepo    repository.RoleRepository
+	refreshRepo repository.RefreshTokenRepository
+	auditRepo   repository.AuditRepository
+}
+
+func NewAdminUseCase(userRepo repository.UserRepository, roleRepo repository.RoleRepository, refreshRepo repository.RefreshTokenRepository, auditRepo repository.AuditRepository) AdminUseCase {
+	return &adminUseCase{
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		refreshRepo: refreshRepo,
+		auditRepo:   auditRepo,
+	}
+}
+
+func (uc *adminUseCase) ListUsers(limit, offset int) (*entity.Page[entity.UserWithRoles], error) {
+	limit, offset = normalizePage(limit, offset)
+	users, total, err := uc.userRepo.List(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]entity.UserWithRoles, len(users))
+	for i, user := range users {
+		roles, err := uc.roleRepo.RolesForUser(user.UserID)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = entity.UserWithRoles{User: user, Roles: roles}
+	}
+	return &entity.Page[entity.UserWithRoles]{Items: items, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+func (uc *adminUseCase) ListRoles() ([]entity.Role, error) {
+	return uc.roleRepo.ListRoles()
+}
+
+// SetUserRoles replaces a user's roles. The user's tokens are invalidated so
+// that a removed permission stops working immediately rather than when the
+// current access token expires.
+func (uc *adminUseCase) SetUserRoles(actor *entity.User, userID int, roles []string, client entity.ClientInfo) error {
+	if actor.UserID == userID {
+		return ErrCannotModifySelf
+	}
+	if _, err := uc.userRepo.FindByID(userID); err != nil {
+		return err
+	}
+
+	if err := uc.roleRepo.SetUserRoles(userID, roles, actor.UserID); err != nil {
+		return err
+	}
+	if err := uc.endSessions(userID); err != nil {
+		return err
+	}
+	uc.audit(actor, userID, entity.AuditRolesChanged, client, "roles="+strings.Join(roles, ","))
+	return nil
+}
+
+// SuspendUser deactivates an account and ends all of its sessions. Only
+// administrators may suspend other administrators.
+func (uc *adminUseCase) SuspendUser(actor *entity.User, userID int, reason string, client entity.ClientInfo) error {
+	if err := uc.checkModerationTarget(actor, userID); err != nil {
+		return err
+	}
+
+	if err := uc.userRepo.Suspend(userID, time.Now()); err != nil {
+		return err
+	}
+	if err := uc.endSessions(userID); err != nil {
+		return err
+	}
+	uc.audit(actor, userID, entity.AuditUserSuspended, client, fmt.Sprintf("reason=%q", reason))
+	return nil
+}
+
+// UnsuspendUser lifts a suspension. Accounts that are not suspended, such as
+// ones their owner deactivated, are left alone.
+func (uc *adminUseCase) UnsuspendUser(actor *entity.User, userID int, client entity.ClientInfo) error {
+	if err := uc.checkModerationTarget(actor, userID); err != nil {
+		return err
+	}
+
+	if err := uc.userRepo.Unsuspend(userID); err != nil {
+		return err
+	}
+	uc.audit(actor, userID, entity.AuditUserUnsuspended, client, "")
+	return nil
+}
+
+func (uc *adminUseCase) ListAuditLogs(limit, offset int) (*entity.Page[entity.AuditLog], error) {
+	limit, offset = normalizePage(limit, offset)
+	entries, total, err := uc.auditRepo.List(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.Page[entity.AuditLog]{Items: entries, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+func (uc *adminUseCase) checkModerationTarget(actor *entity.User, userID int) error {
+	if actor.UserID == userID {
+		return ErrCannotModifySelf
+	}
+	if _, err := uc.userRepo.FindByID(userID); err != nil {
+		return err
+	}
+
+	targetRoles, err := uc.roleRepo.RolesForUser(userID)
+	if err != nil {
+		return err
+	}
+	if !containsString(targetRoles, entity.RoleAdmin) {
+		return nil
+	}
+	actorRoles, err := uc.roleRepo.RolesForUser(actor.UserID)
+	if err != nil {
+		return err
+	}
+	if !containsString(actorRoles, entity.RoleAdmin) {
+		return ErrInsufficientRight
+	}
+	return nil
+}
+
+func (uc *adminUseCase) endSessions(userID int) error {
+	if err := uc.userRepo.IncrementTokenVersion(userID); err != nil {
+		return err
+	}
+	return uc.refreshRepo.RevokeAllForUser(userID)
+}
+
+func (uc *adminUseCase) audit(actor *entity.User, userID int, action string, client entity.ClientInfo, details string) {
+	if details != "" {
+		details += " "
+	}
+	details += fmt.Sprintf("by=%d", actor.UserID)
+	entry := &entity.AuditLog{UserID: &userID, Action: action, IP: client.IP, Details: details}
+	if err := uc.auditRepo.Record(entry); err != nil {
+		log.Printf("failed to write audit log %q: %v", action, err)
+	}
+}
+
+// normalizePage clamps pagination parameters to sane values
+func normalizePage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+// internal/usecase/api_key_usecase.go
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+	"github.com/almatkai/book-exchange-backend/pkg/utils"
+)
+
+var (
+	ErrAPIKeyNotFound    = repository.ErrAPIKeyNotFound
+	ErrInvalidAPIKeyName = errors.New("api key name is required (max 100 characters)")
+	ErrInvalidScope      = errors.New("scopes must be permissions the user holds")
+	ErrTooManyAPIKeys    = errors.New("too many api keys")
+)
+
+const (
+	// maxAPIKeysPerUser bounds the number of unrevoked keys per account
+	maxAPIKeysPerUser = 25
+	// apiKeyDisplayLength is how much of a key is kept to identify it
+	apiKeyDisplayLength = len(entity.APIKeyPrefix) + 8
+)
+
+// APIKeyUseCase manages personal API keys
+type APIKeyUseCase interface {
+	CreateAPIKey(user *entity.User, name string, scopes []string, expiresIn time.Duration, client entity.ClientInfo) (*entity.CreatedAPIKey, error)
+	ListAPIKeys(userID int) ([]entity.APIKey, error)
+	RevokeAPIKey(userID int, id uuid.UUID, client entity.ClientInfo) error
+}
+
+type apiKeyUseCase struct {
+	keyRepo   repository.APIKeyRepository
+	roleRepo  repository.RoleRepository
+	auditRepo repository.AuditRepository
+}
+
+func NewAPIKeyUseCase(keyRepo repository.APIKeyRepository, roleRepo repository.RoleRepository, auditRepo repository.AuditRepository) APIKeyUseCase {
+	return &apiKeyUseCase{
+		keyRepo:   keyRepo,
+		roleRepo:  roleRepo,
+		auditRepo: auditRepo,
+	}
+}
+
+// CreateAPIKey issues a key limited to the given scopes. A key can never be
+// granted a permission its owner does not hold; expiresIn of zero means the
+// key does not expire.
+func (uc *apiKeyUseCase) CreateAPIKey(user *entity.User, name string, scopes []string, expiresIn time.Duration, client entity.ClientInfo) (*entity.CreatedAPIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return nil, ErrInvalidAPIKeyName
+	}
+	if err := uc.checkScopes(user.UserID, scopes); err != nil {
+		return nil, err
+	}
+
+	count, err := uc.keyRepo.CountActiveForUser(user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxAPIKeysPerUser {
+		return nil, ErrTooManyAPIKeys
+	}
+
+	secret, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	raw := entity.APIKeyPrefix + secret
+
+	key := entity.APIKey{
+		UserID:  user.UserID,
+		Name:    name,
+		Prefix:  raw[:apiKeyDisplayLength],
+		KeyHash: utils.HashToken(raw),
+		Scopes:  dedupe(scopes),
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		key.ExpiresAt = &expiresAt
+	}
+	if err := uc.keyRepo.Create(&key); err != nil {
+		return nil, err
+	}
+
+	uc.audit(user.UserID, entity.AuditAPIKeyCreated, client, fmt.Sprintf("key=%s name=%q", key.ID, name))
+	return &entity.CreatedAPIKey{Key: raw, APIKey: key}, nil
+}
+
+func (uc *apiKeyUseCase) ListAPIKeys(userID int) ([]entity.APIKey, error) {
+	return uc.keyRepo.ListActiveForUser(userID)
+}
+
+func (uc *apiKeyUseCase) RevokeAPIKey(userID int, id uuid.UUID, client entity.ClientInfo) error {
+	if err := uc.keyRepo.Revoke(userID, id); err != nil {
+		return err
+	}
+	uc.audit(userID, entity.AuditAPIKeyRevoked, client, "key="+id.String())
+	return nil
+}
+
+func (uc *apiKeyUseCase) checkScopes(userID int, scopes []string) error {
+	if len(scopes) == 0 {
+		return ErrInvalidScope
+	}
+	held, err := uc.roleRepo.PermissionsForUser(userID)
+	if err != nil {
+		return err
+	}
+	for _, scope := range scopes {
+		if !containsString(held, scope) {
+			return ErrInvalidScope
+		}
+	}
+	return nil
+}
+
+func (uc *apiKeyUseCase) audit(userID int, action string, client entity.ClientInfo, details string) {
+	entry := &entity.AuditLog{UserID: &userID, Action: action, IP: client.IP, Details: details}
+	if err := uc.auditRepo.Record(entry); err != nil {
+		log.Printf("failed to write audit log %q: %v", action, err)
+	}
+}
+
+func dedupe(values []string) []string {
+	var out []string
+	for _, value := range values {
+		if !containsString(out, value) {
+			out = append(out, value)
+		}
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+// internal/usecase/block_usecase.go
+package usecase
+
+import (
+	"errors"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+)
+
+var (
+	ErrCannotBlockSelf = errors.New("you cannot block yourself")
+	// ErrBlocked means one of the users blocked the other
+	ErrBlocked = repository.ErrBlocked
+)
+
+// BlockUseCase manages block lists. Features where one user acts on another
+// call CheckInteraction before acting. Messages, exchange requests, comments
+// and reviews are also guarded in the database: inserting one between users
+// who blocked each other fails with ErrBlocked.
+type BlockUseCase interface {
+	Block(user *entity.User, username string) error
+	Unblock(user *entity.User, username string) error
+	ListBlocked(user *entity.User, limit, offset int) (*entity.Page[entity.PublicProfile], error)
+	// CheckInteraction returns ErrBlocked when actorID and targetID blocked
+	// each other in either direction
+	CheckInteraction(actorID, targetID int) error
+}
+
+type blockUseCase struct {
+	userRepo  repository.UserRepository
+	blockRepo repository.BlockRepository
+}
+
+func NewBlockUseCase(userRepo repository.UserRepository, blockRepo repository.BlockRepository) BlockUseCase {
+	return &blockUseCase{
+		userRepo:  userRepo,
+		blockRepo: blockRepo,
+	}
+}
+
+// Block blocks a user and ends follows between the two
+func (uc *blockUseCase) Block(user *entity.User, username string) error {
+	target, err := uc.userRepo.FindByUsername(username)
+	if err != nil {
+		return err
+	}
+	if target.UserID == user.UserID {
+		return ErrCannotBlockSelf
+	}
+	return uc.blockRepo.Block(user.UserID, target.UserID)
+}
+
+func (uc *blockUseCase) Unblock(user *entity.User, username string) error {
+	target, err := uc.userRepo.FindByUsername(username)
+	if err != nil {
+		return err
+	}
+	return uc.blockRepo.Unblock(user.UserID, target.UserID)
+}
+
+func (uc *blockUseCase) ListBlocked(user *entity.User, limit, offset int) (*entity.Page[entity.PublicProfile], error) {
+	limit, offset = normalizePage(limit, offset)
+	users, total, err := uc.blockRepo.ListBlocked(user.UserID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.Page[entity.PublicProfile]{Items: publicProfiles(users), Total: total, Limit: limit, Offset: offset}, nil
+}
+
+func (uc *blockUseCase) CheckInteraction(actorID, targetID int) error {
+	blocked, err := uc.blockRepo.EitherBlocked(actorID, targetID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+	return nil
+}
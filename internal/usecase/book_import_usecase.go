@@ -0,0 +1,375 @@
+// internal/usecase/book_import_usecase.go
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+	"github.com/almatkai/book-exchange-backend/pkg/isbn"
+)
+
+const (
+	maxImportRows = 5000
+	// maxImportErrors bounds the row errors kept for a job
+	maxImportErrors = 1000
+	// importProgressInterval is how many rows are processed between saves
+	importProgressInterval = 25
+	// importStaleAfter is how long a running job may go without progress
+	// before it is considered abandoned
+	importStaleAfter = 15 * time.Minute
+)
+
+var (
+	ErrImportNotFound    = repository.ErrImportNotFound
+	ErrInvalidImportFile = errors.New("file must be a CSV export with a header row and title and author columns")
+	ErrEmptyImport       = errors.New("file has no rows to import")
+	ErrImportTooLarge    = fmt.Errorf("file has more than %d rows", maxImportRows)
+	ErrImportInProgress  = repository.ErrImportInProgress
+)
+
+// importColumns maps header names, lowercased, onto book fields. Goodreads
+// exports use Title, Author, ISBN, ISBN13 and Year Published.
+var importColumns = map[string]string{
+	"title":       "title",
+	"book title":  "title",
+	"author":      "author",
+	"authors":     "author",
+	"author(s)":   "author",
+	"isbn":        "isbn",
+	"isbn10":      "isbn",
+	"isbn-10":     "isbn",
+	"isbn13":      "isbn13",
+	"isbn-13":     "isbn13",
+	"condition":   "condition",
+	"description": "description",
+	"notes":       "description",
+
+	"genre":            "genre",
+	"language":         "language",
+	"year":             "year",
+	"year published":   "year",
+	"publication year": "year",
+	"publication_year": "year",
+	"image_url":        "image_url",
+	"cover":            "image_url",
+}
+
+// BookImportUseCase adds many books at once from a CSV file
+type BookImportUseCase interface {
+	// StartImport checks the file and imports its rows in the background
+	StartImport(user *entity.User, data []byte) (*entity.ImportJob, error)
+	GetImport(user *entity.User, id uuid.UUID) (*entity.ImportJob, error)
+	ListImports(user *entity.User, limit, offset int) (*entity.Page[entity.ImportJob], error)
+	// FailStaleImports fails jobs abandoned by a stopped server
+	FailStaleImports() (int64, error)
+}
+
+type bookImportUseCase struct {
+	importRepo repository.ImportRepository
+	bookRepo   repository.BookRepository
+	books      BookUseCase
+}
+
+func NewBookImportUseCase(importRepo repository.ImportRepository, bookRepo repository.BookRepository, books BookUseCase) BookImportUseCase {
+	return &bookImportUseCase{
+		importRepo: importRepo,
+		bookRepo:   bookRepo,
+		books:      books,
+	}
+}
+
+// importRow is a parsed line of an import file; number is its line in the
+// file. A non-empty skip explains why the row is left out, and a non-empty
+// invalid why it cannot be imported.
+type importRow struct {
+	number  int
+	book    entity.CreateBookRequest
+	skip    string
+	invalid string
+}
+
+func (uc *bookImportUseCase) StartImport(user *entity.User, data []byte) (*entity.ImportJob, error) {
+	format, rows, err := parseImportFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &entity.ImportJob{
+		UserID:    user.UserID,
+		Format:    format,
+		Status:    entity.ImportPending,
+		TotalRows: len(rows),
+	}
+	if err := uc.importRepo.Create(job); err != nil {
+		return nil, err
+	}
+
+	owner := *user
+	started := *job
+	go uc.run(&owner, &started, rows)
+	return job, nil
+}
+
+func (uc *bookImportUseCase) GetImport(user *entity.User, id uuid.UUID) (*entity.ImportJob, error) {
+	return uc.importRepo.FindByID(user.UserID, id)
+}
+
+func (uc *bookImportUseCase) ListImports(user *entity.User, limit, offset int) (*entity.Page[entity.ImportJob], error) {
+	limit, offset = normalizePage(limit, offset)
+	jobs, total, err := uc.importRepo.ListByUser(user.UserID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.Page[entity.ImportJob]{Items: jobs, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+func (uc *bookImportUseCase) FailStaleImports() (int64, error) {
+	return uc.importRepo.FailStale(time.Now().Add(-importStaleAfter), "the import was interrupted, upload the file again to import the remaining rows")
+}
+
+// run imports the rows one by one. Books whose ISBN is already in the
+// library, or earlier in the file, are skipped. A panic fails the job
+// instead of stopping the server.
+func (uc *bookImportUseCase) run(user *entity.User, job *entity.ImportJob, rows []importRow) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("import %s: panic after %d rows: %v\n%s", job.ID, job.ProcessedRows, r, debug.Stack())
+			job.Errors = append(job.Errors, entity.ImportRowError{Message: "the import stopped unexpectedly, upload the file again to import the remaining rows"})
+			uc.finish(job, entity.ImportFailed)
+		}
+	}()
+
+	job.Status = entity.ImportRunning
+	uc.save(job)
+
+	seen, err := uc.ownedISBNs(user.UserID)
+	if err != nil {
+		log.Printf("import %s: failed to load existing books: %v", job.ID, err)
+		uc.finish(job, entity.ImportFailed)
+		return
+	}
+
+	for i, row := range rows {
+		uc.importRow(user, job, row, seen)
+		job.ProcessedRows++
+		if (i+1)%importProgressInterval == 0 {
+			uc.save(job)
+		}
+	}
+	uc.finish(job, entity.ImportCompleted)
+}
+
+func (uc *bookImportUseCase) importRow(user *entity.User, job *entity.ImportJob, row importRow, seen map[string]bool) {
+	if row.skip != "" {
+		job.Skipped++
+		addImportError(job, row.number, row.skip, true)
+		return
+	}
+	if row.invalid != "" {
+		job.Failed++
+		addImportError(job, row.number, row.invalid, false)
+		return
+	}
+
+	var normalized string
+	if strings.TrimSpace(row.book.ISBN) != "" {
+		var err error
+		if normalized, err = isbn.Normalize(row.book.ISBN); err != nil {
+			job.Failed++
+			addImportError(job, row.number, ErrInvalidISBN.Error(), false)
+			return
+		}
+		if seen[normalized] {
+			job.Skipped++
+			addImportError(job, row.number, "a book with ISBN "+normalized+" is already in your library", true)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := uc.books.CreateBook(ctx, user, row.book); err != nil {
+		job.Failed++
+		if isBookInputError(err) {
+			addImportError(job, row.number, err.Error(), false)
+		} else {
+			log.Printf("import %s: failed to save row %d: %v", job.ID, row.number, err)
+			addImportError(job, row.number, "the book could not be saved", false)
+		}
+		return
+	}
+	job.Imported++
+	if normalized != "" {
+		seen[normalized] = true
+	}
+}
+
+// ownedISBNs returns the normalized ISBNs of the user's books
+func (uc *bookImportUseCase) ownedISBNs(userID int) (map[string]bool, error) {
+	stored, err := uc.bookRepo.ListOwnerISBNs(userID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(stored))
+	for _, value := range stored {
+		if normalized, err := isbn.Normalize(value); err == nil {
+			seen[normalized] = true
+		}
+	}
+	return seen, nil
+}
+
+func (uc *bookImportUseCase) finish(job *entity.ImportJob, status string) {
+	now := time.Now()
+	job.Status = status
+	job.FinishedAt = &now
+	uc.save(job)
+}
+
+func (uc *bookImportUseCase) save(job *entity.ImportJob) {
+	if err := uc.importRepo.Save(job); err != nil {
+		log.Printf("import %s: failed to save progress: %v", job.ID, err)
+	}
+}
+
+func addImportError(job *entity.ImportJob, row int, message string, skipped bool) {
+	if len(job.Errors) < maxImportErrors {
+		job.Errors = append(job.Errors, entity.ImportRowError{Row: row, Message: message, Skipped: skipped})
+	}
+}
+
+// isBookInputError reports whether CreateBook rejected the row's content,
+// as opposed to failing to store it
+func isBookInputError(err error) bool {
+	switch err {
+	case ErrInvalidTitle, ErrInvalidAuthor, ErrInvalidISBN, ErrInvalidCondition, ErrInvalidDescription,
+		ErrInvalidGenre, ErrInvalidLanguage, ErrInvalidYear, ErrInvalidImageURL, ErrMetadataUnavailable:
+		return true
+	}
+	return false
+}
+
+// parseImportFile reads a CSV file with a header row. Goodreads exports are
+// recognised by their Exclusive Shelf column; books on the to-read shelf
+// are not owned and are skipped. Rows without a condition default to Good.
+func parseImportFile(data []byte) (string, []importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = false
+
+	header, err := reader.Read()
+	if err != nil {
+		return "", nil, ErrInvalidImportFile
+	}
+	columns := map[string]int{}
+	shelf := -1
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if field, ok := importColumns[name]; ok {
+			if _, taken := columns[field]; !taken {
+				columns[field] = i
+			}
+		}
+		if name == "exclusive shelf" {
+			shelf = i
+		}
+	}
+	if _, ok := columns["title"]; !ok {
+		return "", nil, ErrInvalidImportFile
+	}
+	if _, ok := columns["author"]; !ok {
+		return "", nil, ErrInvalidImportFile
+	}
+	format := entity.ImportFormatCSV
+	if shelf >= 0 {
+		format = entity.ImportFormatGoodreads
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, ErrInvalidImportFile
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		if len(rows) == maxImportRows {
+			return "", nil, ErrImportTooLarge
+		}
+
+		value := func(field string) string {
+			i, ok := columns[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return cleanImportValue(record[i])
+		}
+		line, _ := reader.FieldPos(0)
+		row := importRow{number: line, book: entity.CreateBookRequest{
+			Title:       value("title"),
+			Author:      value("author"),
+			ISBN:        value("isbn13"),
+			Condition:   value("condition"),
+			Description: value("description"),
+			Genre:       value("genre"),
+			Language:    value("language"),
+			ImageURL:    value("image_url"),
+		}}
+		if row.book.ISBN == "" {
+			row.book.ISBN = value("isbn")
+		}
+		if year := value("year"); year != "" {
+			if row.book.PublicationYear, err = strconv.Atoi(year); err != nil {
+				row.invalid = ErrInvalidYear.Error()
+			}
+		}
+		if row.book.Condition == "" {
+			row.book.Condition = entity.ConditionGood
+		}
+		if shelf >= 0 && shelf < len(record) && strings.EqualFold(strings.TrimSpace(record[shelf]), "to-read") {
+			row.skip = "book is on the to-read shelf"
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return "", nil, ErrEmptyImport
+	}
+	return format, rows, nil
+}
+
+// cleanImportValue trims a cell. Goodreads wraps ISBNs in ="..." so that
+// spreadsheets keep leading zeros.
+func cleanImportValue(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "=\"") && strings.HasSuffix(value, "\"") {
+		value = strings.TrimSpace(value[2 : len(value)-1])
+	}
+	return value
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
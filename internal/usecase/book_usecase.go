@@ -1 +1,416 @@
+// internal/usecase/book_usecase.go
 package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+	"github.com/almatkai/book-exchange-backend/pkg/bookmeta"
+	"github.com/almatkai/book-exchange-backend/pkg/isbn"
+)
+
+var (
+	ErrBookNotFound       = repository.ErrBookNotFound
+	ErrBookInExchange     = repository.ErrBookInExchange
+	ErrNotBookOwner       = errors.New("only the owner can change this book")
+	ErrInvalidTitle       = errors.New("title is required and must be at most 255 characters on a single line")
+	ErrInvalidAuthor      = errors.New("author is required and must be at most 255 characters on a single line")
+	ErrInvalidISBN        = errors.New("isbn must be a valid ISBN-10 or ISBN-13")
+	ErrInvalidCondition   = errors.New("condition must be one of: " + strings.Join(entity.BookConditions, ", "))
+	ErrInvalidDescription = errors.New("description must be at most 5000 characters")
+	ErrInvalidGenre       = errors.New("genre must be at most 50 characters on a single line")
+	ErrInvalidLanguage    = errors.New("language must be at most 50 characters on a single line")
+	ErrInvalidYear        = errors.New("publication_year must be a positive year no later than the current one")
+	ErrInvalidImageURL    = errors.New("image_url must be an http or https URL of at most 255 characters")
+	ErrInvalidNote        = errors.New("note must be at most 1000 characters")
+	ErrEmptyBookUpdate    = errors.New("no book fields to update")
+	ErrMetadataNotFound   = errors.New("no book details found for this ISBN")
+	// ErrMetadataUnavailable means the book catalog could not be asked
+	ErrMetadataUnavailable = errors.New("book details are unavailable right now, enter title and author yourself")
+)
+
+// Book field limits, in characters
+const (
+	maxTitleLength       = 255
+	maxAuthorLength      = 255
+	maxDescriptionLength = 5000
+	maxGenreLength       = 50
+	maxLanguageLength    = 50
+	maxImageURLLength    = 255
+	maxNoteLength        = 1000
+)
+
+// BookUseCase manages the books users list for exchange
+type BookUseCase interface {
+	CreateBook(ctx context.Context, user *entity.User, req entity.CreateBookRequest) (*entity.Book, error)
+	GetBook(viewer *entity.User, bookID int) (*entity.Book, error)
+	ListMyBooks(user *entity.User, limit, offset int) (*entity.Page[entity.Book], error)
+	ListUserBooks(viewer *entity.User, username string, availableOnly bool, limit, offset int) (*entity.Page[entity.Book], error)
+	FindByISBN(viewer *entity.User, value string, limit, offset int) (*entity.Page[entity.Book], error)
+	UpdateBook(user *entity.User, bookID int, update entity.UpdateBookRequest) (*entity.Book, error)
+	SetAvailability(user *entity.User, bookID int, available bool) (*entity.Book, error)
+	DeleteBook(user *entity.User, bookID int) error
+	RecordCondition(user *entity.User, bookID int, req entity.RecordConditionRequest) (*entity.BookConditionChange, error)
+	ConditionHistory(viewer *entity.User, bookID int, limit, offset int) (*entity.Page[entity.BookConditionChange], error)
+	LookupMetadata(ctx context.Context, value string) (*entity.BookMetadata, error)
+}
+
+// BookSettings configures how books are listed
+type BookSettings struct {
+	// LocationPrecisionKm is the grid size books are placed on, around
+	// their owner's position
+	LocationPrecisionKm float64
+}
+
+type bookUseCase struct {
+	bookRepo repository.BookRepository
+	userRepo repository.UserRepository
+	blocks   BlockUseCase
+	metadata bookmeta.Provider
+	settings BookSettings
+}
+
+// NewBookUseCase creates a BookUseCase; metadata may be nil when no book
+// catalog is configured
+func NewBookUseCase(bookRepo repository.BookRepository, userRepo repository.UserRepository, blocks BlockUseCase, metadata bookmeta.Provider, settings BookSettings) BookUseCase {
+	return &bookUseCase{
+		bookRepo: bookRepo,
+		userRepo: userRepo,
+		blocks:   blocks,
+		metadata: metadata,
+		settings: settings,
+	}
+}
+
+// CreateBook lists a book of the user, available for exchange and placed at
+// the user's approximate location. With an ISBN, a missing title or author
+// is filled in from the book catalog, along with the language, publication
+// year and cover when those are missing too.
+func (uc *bookUseCase) CreateBook(ctx context.Context, user *entity.User, req entity.CreateBookRequest) (*entity.Book, error) {
+	if strings.TrimSpace(req.ISBN) != "" && (strings.TrimSpace(req.Title) == "" || strings.TrimSpace(req.Author) == "") {
+		meta, err := uc.LookupMetadata(ctx, req.ISBN)
+		switch err {
+		case nil:
+			if strings.TrimSpace(req.Title) == "" {
+				req.Title = meta.Title
+			}
+			if strings.TrimSpace(req.Author) == "" {
+				req.Author = strings.Join(meta.Authors, ", ")
+			}
+			fillFromMetadata(&req, meta)
+		case ErrMetadataNotFound:
+			// Validation below reports the missing field
+		default:
+			return nil, err
+		}
+	}
+
+	update := entity.UpdateBookRequest{
+		Title:           &req.Title,
+		Author:          &req.Author,
+		ISBN:            &req.ISBN,
+		Condition:       &req.Condition,
+		Description:     &req.Description,
+		Genre:           &req.Genre,
+		Language:        &req.Language,
+		PublicationYear: &req.PublicationYear,
+		ImageURL:        &req.ImageURL,
+	}
+	if err := validateBookUpdate(&update); err != nil {
+		return nil, err
+	}
+
+	book := &entity.Book{
+		OwnerID:     user.UserID,
+		Title:       *update.Title,
+		Author:      *update.Author,
+		Condition:   *update.Condition,
+		Description: *update.Description,
+		Genre:       *update.Genre,
+		Language:    *update.Language,
+		ImageURL:    *update.ImageURL,
+		IsAvailable: true,
+	}
+	if *update.ISBN != "" {
+		book.ISBN = update.ISBN
+	}
+	if *update.PublicationYear != 0 {
+		book.PublicationYear = update.PublicationYear
+	}
+	if location := user.Coordinates(); location != nil {
+		approx := approximateLocation(*location, uc.settings.LocationPrecisionKm)
+		book.Latitude, book.Longitude = &approx.Latitude, &approx.Longitude
+	}
+	if err := uc.bookRepo.Create(book); err != nil {
+		return nil, err
+	}
+	return uc.bookRepo.FindByID(book.BookID)
+}
+
+// GetBook returns a book; books of users blocked either way are not found
+func (uc *bookUseCase) GetBook(viewer *entity.User, bookID int) (*entity.Book, error) {
+	book, err := uc.bookRepo.FindByID(bookID)
+	if err != nil {
+		return nil, err
+	}
+	if book.OwnerID != viewer.UserID {
+		if err := uc.blocks.CheckInteraction(viewer.UserID, book.OwnerID); err != nil {
+			if err == ErrBlocked {
+				return nil, ErrBookNotFound
+			}
+			return nil, err
+		}
+	}
+	return book, nil
+}
+
+func (uc *bookUseCase) ListMyBooks(user *entity.User, limit, offset int) (*entity.Page[entity.Book], error) {
+	return uc.listBooks(user.UserID, false, limit, offset)
+}
+
+// ListUserBooks returns another user's books, unless one of them blocked the other
+func (uc *bookUseCase) ListUserBooks(viewer *entity.User, username string, availableOnly bool, limit, offset int) (*entity.Page[entity.Book], error) {
+	owner, err := uc.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if owner.UserID != viewer.UserID {
+		if err := uc.blocks.CheckInteraction(viewer.UserID, owner.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return uc.listBooks(owner.UserID, availableOnly, limit, offset)
+}
+
+// FindByISBN returns the available books with an ISBN, given in any form.
+// Books stored under the ISBN-10 of the same edition are found as well.
+func (uc *bookUseCase) FindByISBN(viewer *entity.User, value string, limit, offset int) (*entity.Page[entity.Book], error) {
+	isbns, err := isbn.Variants(value)
+	if err != nil {
+		return nil, ErrInvalidISBN
+	}
+	limit, offset = normalizePage(limit, offset)
+	books, total, err := uc.bookRepo.ListAvailableByISBN(viewer.UserID, isbns, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.Page[entity.Book]{Items: books, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+func (uc *bookUseCase) listBooks(ownerID int, availableOnly bool, limit, offset int) (*entity.Page[entity.Book], error) {
+	limit, offset = normalizePage(limit, offset)
+	books, total, err := uc.bookRepo.ListByOwner(ownerID, availableOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.Page[entity.Book]{Items: books, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+// UpdateBook validates and stores the fields set in update
+func (uc *bookUseCase) UpdateBook(user *entity.User, bookID int, update entity.UpdateBookRequest) (*entity.Book, error) {
+	if update == (entity.UpdateBookRequest{}) {
+		return nil, ErrEmptyBookUpdate
+	}
+	if err := validateBookUpdate(&update); err != nil {
+		return nil, err
+	}
+	if _, err := uc.ownedBook(user, bookID); err != nil {
+		return nil, err
+	}
+	if err := uc.bookRepo.Update(bookID, update); err != nil {
+		return nil, err
+	}
+	return uc.bookRepo.FindByID(bookID)
+}
+
+// SetAvailability shows or hides a book from people looking for exchanges
+func (uc *bookUseCase) SetAvailability(user *entity.User, bookID int, available bool) (*entity.Book, error) {
+	book, err := uc.ownedBook(user, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if book.IsAvailable == available {
+		return book, nil
+	}
+	if err := uc.bookRepo.SetAvailable(bookID, available); err != nil {
+		return nil, err
+	}
+	return uc.bookRepo.FindByID(bookID)
+}
+
+func (uc *bookUseCase) DeleteBook(user *entity.User, bookID int) error {
+	if _, err := uc.ownedBook(user, bookID); err != nil {
+		return err
+	}
+	return uc.bookRepo.Delete(bookID)
+}
+
+// RecordCondition sets the condition of a book of the user and adds it to
+// the book's condition history, with an optional note
+func (uc *bookUseCase) RecordCondition(user *entity.User, bookID int, req entity.RecordConditionRequest) (*entity.BookConditionChange, error) {
+	condition, ok := normalizeCondition(req.Condition)
+	if !ok {
+		return nil, ErrInvalidCondition
+	}
+	note, err := cleanTextField(&req.Note, maxNoteLength, true, ErrInvalidNote)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uc.ownedBook(user, bookID); err != nil {
+		return nil, err
+	}
+
+	change := &entity.BookConditionChange{BookID: bookID, Condition: condition, Note: *note}
+	if err := uc.bookRepo.RecordCondition(change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// ConditionHistory returns how the condition of a book changed, newest first
+func (uc *bookUseCase) ConditionHistory(viewer *entity.User, bookID int, limit, offset int) (*entity.Page[entity.BookConditionChange], error) {
+	if _, err := uc.GetBook(viewer, bookID); err != nil {
+		return nil, err
+	}
+	limit, offset = normalizePage(limit, offset)
+	changes, total, err := uc.bookRepo.ListConditionHistory(bookID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.Page[entity.BookConditionChange]{Items: changes, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+// LookupMetadata fetches the details of an edition from the book catalog
+func (uc *bookUseCase) LookupMetadata(ctx context.Context, value string) (*entity.BookMetadata, error) {
+	normalized, err := isbn.Normalize(value)
+	if err != nil {
+		return nil, ErrInvalidISBN
+	}
+	if uc.metadata == nil {
+		return nil, ErrMetadataUnavailable
+	}
+
+	meta, err := uc.metadata.Lookup(ctx, normalized)
+	if err != nil {
+		if errors.Is(err, bookmeta.ErrNotFound) {
+			return nil, ErrMetadataNotFound
+		}
+		if !errors.Is(err, bookmeta.ErrUnavailable) {
+			log.Printf("book metadata lookup for %s failed: %v", normalized, err)
+		}
+		return nil, ErrMetadataUnavailable
+	}
+	return &entity.BookMetadata{
+		ISBN:            normalized,
+		Title:           meta.Title,
+		Authors:         meta.Authors,
+		CoverURL:        meta.CoverURL,
+		PublicationYear: meta.PublicationYear,
+		Language:        meta.Language,
+		PageCount:       meta.PageCount,
+	}, nil
+}
+
+// ownedBook loads a book the user is about to change
+func (uc *bookUseCase) ownedBook(user *entity.User, bookID int) (*entity.Book, error) {
+	book, err := uc.GetBook(user, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if book.OwnerID != user.UserID {
+		return nil, ErrNotBookOwner
+	}
+	return book, nil
+}
+
+// validateBookUpdate trims and checks the fields set in update. Title and
+// author cannot be cleared; ISBNs are converted to bare ISBN-13s. The
+// publication year may be 0, for unknown, but not in the future.
+func validateBookUpdate(update *entity.UpdateBookRequest) error {
+	var err error
+	if update.Title, err = cleanTextField(update.Title, maxTitleLength, false, ErrInvalidTitle); err != nil {
+		return err
+	}
+	if update.Title != nil && *update.Title == "" {
+		return ErrInvalidTitle
+	}
+	if update.Author, err = cleanTextField(update.Author, maxAuthorLength, false, ErrInvalidAuthor); err != nil {
+		return err
+	}
+	if update.Author != nil && *update.Author == "" {
+		return ErrInvalidAuthor
+	}
+	if update.Description, err = cleanTextField(update.Description, maxDescriptionLength, true, ErrInvalidDescription); err != nil {
+		return err
+	}
+	if update.Genre, err = cleanTextField(update.Genre, maxGenreLength, false, ErrInvalidGenre); err != nil {
+		return err
+	}
+	if update.Language, err = cleanTextField(update.Language, maxLanguageLength, false, ErrInvalidLanguage); err != nil {
+		return err
+	}
+	if update.PublicationYear != nil && (*update.PublicationYear < 0 || *update.PublicationYear > time.Now().Year()) {
+		return ErrInvalidYear
+	}
+	if update.ImageURL, err = cleanTextField(update.ImageURL, maxImageURLLength, false, ErrInvalidImageURL); err != nil {
+		return err
+	}
+	if update.ImageURL != nil && *update.ImageURL != "" && !isWebURL(*update.ImageURL) {
+		return ErrInvalidImageURL
+	}
+	if update.ISBN != nil {
+		value := strings.TrimSpace(*update.ISBN)
+		if value != "" {
+			if value, err = isbn.Normalize(value); err != nil {
+				return ErrInvalidISBN
+			}
+		}
+		update.ISBN = &value
+	}
+	if update.Condition != nil {
+		condition, ok := normalizeCondition(*update.Condition)
+		if !ok {
+			return ErrInvalidCondition
+		}
+		update.Condition = &condition
+	}
+	return nil
+}
+
+// normalizeCondition accepts a condition in any case, e.g. "like new"
+func normalizeCondition(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, condition := range entity.BookConditions {
+		if strings.EqualFold(value, condition) {
+			return condition, true
+		}
+	}
+	return "", false
+}
+
+// fillFromMetadata copies catalog details the request leaves out. Values
+// the book columns cannot hold are ignored.
+func fillFromMetadata(req *entity.CreateBookRequest, meta *entity.BookMetadata) {
+	if strings.TrimSpace(req.Language) == "" && utf8.RuneCountInString(meta.Language) <= maxLanguageLength {
+		req.Language = meta.Language
+	}
+	if req.PublicationYear == 0 && meta.PublicationYear <= time.Now().Year() {
+		req.PublicationYear = meta.PublicationYear
+	}
+	if strings.TrimSpace(req.ImageURL) == "" && len(meta.CoverURL) <= maxImageURLLength && isWebURL(meta.CoverURL) {
+		req.ImageURL = meta.CoverURL
+	}
+}
+
+// isWebURL reports whether value is an absolute http or https URL
+func isWebURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,149 @@
+// internal/usecase/follow_usecase.go
+package usecase
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+)
+
+var (
+	ErrCannotFollowSelf = errors.New("you cannot follow yourself")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+)
+
+const (
+	defaultFeedPageSize = 20
+	maxFeedPageSize     = 50
+)
+
+// FollowUseCase manages who follows whom and builds the activity feed
+type FollowUseCase interface {
+	Follow(user *entity.User, username string) error
+	Unfollow(user *entity.User, username string) error
+	ListFollowers(username string, limit, offset int) (*entity.Page[entity.PublicProfile], error)
+	ListFollowing(username string, limit, offset int) (*entity.Page[entity.PublicProfile], error)
+	Feed(user *entity.User, cursor string, limit int) (*entity.FeedPage, error)
+}
+
+type followUseCase struct {
+	userRepo   repository.UserRepository
+	followRepo repository.FollowRepository
+	blocks     BlockUseCase
+}
+
+func NewFollowUseCase(userRepo repository.UserRepository, followRepo repository.FollowRepository, blocks BlockUseCase) FollowUseCase {
+	return &followUseCase{
+		userRepo:   userRepo,
+		followRepo: followRepo,
+		blocks:     blocks,
+	}
+}
+
+func (uc *followUseCase) Follow(user *entity.User, username string) error {
+	target, err := uc.userRepo.FindByUsername(username)
+	if err != nil {
+		return err
+	}
+	if target.UserID == user.UserID {
+		return ErrCannotFollowSelf
+	}
+	if err := uc.blocks.CheckInteraction(user.UserID, target.UserID); err != nil {
+		return err
+	}
+	return uc.followRepo.Follow(user.UserID, target.UserID)
+}
+
+func (uc *followUseCase) Unfollow(user *entity.User, username string) error {
+	target, err := uc.userRepo.FindByUsername(username)
+	if err != nil {
+		return err
+	}
+	return uc.followRepo.Unfollow(user.UserID, target.UserID)
+}
+
+func (uc *followUseCase) ListFollowers(username string, limit, offset int) (*entity.Page[entity.PublicProfile], error) {
+	return uc.listUsers(username, limit, offset, uc.followRepo.ListFollowers)
+}
+
+func (uc *followUseCase) ListFollowing(username string, limit, offset int) (*entity.Page[entity.PublicProfile], error) {
+	return uc.listUsers(username, limit, offset, uc.followRepo.ListFollowing)
+}
+
+func (uc *followUseCase) listUsers(username string, limit, offset int, list func(userID, limit, offset int) ([]entity.User, int64, error)) (*entity.Page[entity.PublicProfile], error) {
+	target, err := uc.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	limit, offset = normalizePage(limit, offset)
+	users, total, err := list(target.UserID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.Page[entity.PublicProfile]{Items: publicProfiles(users), Total: total, Limit: limit, Offset: offset}, nil
+}
+
+func publicProfiles(users []entity.User) []entity.PublicProfile {
+	profiles := make([]entity.PublicProfile, 0, len(users))
+	for i := range users {
+		profiles = append(profiles, *users[i].PublicProfile())
+	}
+	return profiles
+}
+
+// Feed returns recent listings, reviews and completed exchanges of the users
+// the user follows, newest first. Pass the previous page's NextCursor to
+// continue; unlike offsets, cursors stay stable while new activity arrives.
+func (uc *followUseCase) Feed(user *entity.User, cursor string, limit int) (*entity.FeedPage, error) {
+	if limit <= 0 {
+		limit = defaultFeedPageSize
+	}
+	if limit > maxFeedPageSize {
+		limit = maxFeedPageSize
+	}
+
+	// Without a cursor, start after everything
+	before := entity.FeedCursor{OccurredAt: time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if cursor != "" {
+		decoded, err := decodeFeedCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		before = *decoded
+	}
+
+	// One extra item tells whether there is a next page
+	items, err := uc.followRepo.Feed(user.UserID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &entity.FeedPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeFeedCursor(entity.FeedCursor{OccurredAt: last.OccurredAt, Type: last.Type, ID: last.ID})
+	}
+	return page, nil
+}
+
+func encodeFeedCursor(cursor entity.FeedCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeFeedCursor(value string) (*entity.FeedCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor entity.FeedCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.OccurredAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,262 @@
+// internal/usecase/login_guard.go
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+	"github.com/almatkai/book-exchange-backend/pkg/utils"
+)
+
+var (
+	ErrAccountLocked = errors.New("account temporarily locked")
+)
+
+// ThrottledError is returned when a login is attempted before the backoff
+// delay caused by earlier failures has passed
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// LoginProtectionSettings tunes brute-force protection of the password login.
+// Both accounts and client IPs get FreeAttempts failures without delay; after
+// that each further failure doubles the wait, starting at BackoffBase and
+// capped at BackoffMax. Reaching a lockout threshold blocks the account or IP
+// for LockoutDuration.
+type LoginProtectionSettings struct {
+	FreeAttempts            int
+	BackoffBase             time.Duration
+	BackoffMax              time.Duration
+	AccountLockoutThreshold int
+	IPLockoutThreshold      int
+	LockoutDuration         time.Duration
+	// IPFailureWindow is how long a quiet IP keeps its failure count
+	IPFailureWindow time.Duration
+}
+
+// LoginGuard tracks failed logins per account (in the database, so lockouts
+// survive restarts and are shared across instances) and per IP (in memory).
+// Every use case that checks a password or second factor shares one guard.
+type LoginGuard struct {
+	userRepo  repository.UserRepository
+	auditRepo repository.AuditRepository
+	accounts  AccountUseCase
+	hasher    utils.PasswordHasher
+	settings  LoginProtectionSettings
+	ips       *attemptTracker
+}
+
+func NewLoginGuard(userRepo repository.UserRepository, auditRepo repository.AuditRepository, accounts AccountUseCase, hasher utils.PasswordHasher, settings LoginProtectionSettings) *LoginGuard {
+	return &LoginGuard{
+		userRepo:  userRepo,
+		auditRepo: auditRepo,
+		accounts:  accounts,
+		hasher:    hasher,
+		settings:  settings,
+		ips:       newAttemptTracker(settings.IPFailureWindow),
+	}
+}
+
+// checkIP refuses attempts from an IP that is locked out or backing off
+func (g *LoginGuard) checkIP(ip string, now time.Time) error {
+	record := g.ips.get(ip, now)
+	if now.Before(record.lockedUntil) {
+		return &ThrottledError{RetryAfter: record.lockedUntil.Sub(now)}
+	}
+	if wait := g.backoff(record.failures, record.last, now); wait > 0 {
+		return &ThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// reserveAttempt refuses attempts against a locked or backing off account,
+// and otherwise counts the attempt as failed before the password or code is
+// checked, so that concurrent requests cannot all pass the same check.
+// recordSuccess clears the count once the user has passed every factor.
+func (g *LoginGuard) reserveAttempt(user *entity.User, now time.Time) error {
+	if user.IsLocked(now) {
+		return ErrAccountLocked
+	}
+	if user.LastFailedLoginAt != nil {
+		if wait := g.backoff(user.FailedLoginCount, *user.LastFailedLoginAt, now); wait > 0 {
+			return &ThrottledError{RetryAfter: wait}
+		}
+	}
+
+	failures, err := g.userRepo.ReserveLoginAttempt(user.UserID, user.FailedLoginCount, now)
+	if errors.Is(err, repository.ErrLoginAttemptConflict) {
+		return &ThrottledError{RetryAfter: g.settings.BackoffBase}
+	}
+	if err != nil {
+		return err
+	}
+	user.FailedLoginCount = failures
+	user.LastFailedLoginAt = &now
+	return nil
+}
+
+// checkPassword re-authenticates a signed-in user before a sensitive action.
+// A wrong password counts as a failed login; callers record the success once
+// every other check has passed too.
+func (g *LoginGuard) checkPassword(user *entity.User, password string, client entity.ClientInfo, now time.Time) error {
+	if err := g.reserveAttempt(user, now); err != nil {
+		return err
+	}
+	if err := g.hasher.Verify(password, user.Password); err != nil {
+		g.recordFailure(user, client, now)
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// recordFailure counts a failed attempt from client, against user when the
+// username exists, and applies lockouts. The account's count already includes
+// the attempt, which was reserved before checking it.
+func (g *LoginGuard) recordFailure(user *entity.User, client entity.ClientInfo, now time.Time) {
+	if failures := g.ips.fail(client.IP, now); failures >= g.settings.IPLockoutThreshold {
+		until := now.Add(g.settings.LockoutDuration)
+		g.ips.lock(client.IP, until)
+		g.audit(nil, entity.AuditIPLocked, client, fmt.Sprintf("failed_attempts=%d locked_until=%s", failures, until.Format(time.RFC3339)))
+	}
+
+	if user == nil {
+		return
+	}
+	failures := user.FailedLoginCount
+	if failures < g.settings.AccountLockoutThreshold || user.IsLocked(now) {
+		return
+	}
+
+	until := now.Add(g.settings.LockoutDuration)
+	if err := g.userRepo.LockAccount(user.UserID, until); err != nil {
+		log.Printf("failed to lock user %d: %v", user.UserID, err)
+		return
+	}
+	g.audit(&user.UserID, entity.AuditAccountLocked, client, fmt.Sprintf("failed_attempts=%d locked_until=%s", failures, until.Format(time.RFC3339)))
+	if err := g.accounts.SendAccountUnlock(user); err != nil {
+		log.Printf("failed to send unlock email to user %d: %v", user.UserID, err)
+	}
+}
+
+// recordFirstFactor clears the account's failure count after a correct
+// password at login, unless a second factor is still to come: then it is kept
+// until that passes, so that guessing codes is throttled like passwords
+func (g *LoginGuard) recordFirstFactor(user *entity.User) {
+	if !user.TOTPEnabled() {
+		g.recordSuccess(user)
+	}
+}
+
+// recordSuccess clears the account's failure count once the user has passed
+// every factor
+func (g *LoginGuard) recordSuccess(user *entity.User) {
+	if user.FailedLoginCount == 0 && user.LockedUntil == nil {
+		return
+	}
+	if err := g.userRepo.ResetFailedLogins(user.UserID); err != nil {
+		log.Printf("failed to reset failed logins for user %d: %v", user.UserID, err)
+	}
+}
+
+// backoff returns how much longer the caller must wait after failures
+// consecutive failures, the last of which happened at last
+func (g *LoginGuard) backoff(failures int, last, now time.Time) time.Duration {
+	excess := failures - g.settings.FreeAttempts
+	if excess < 0 {
+		return 0
+	}
+	delay := g.settings.BackoffMax
+	if excess < 32 {
+		if d := g.settings.BackoffBase << uint(excess); d > 0 && d < delay {
+			delay = d
+		}
+	}
+	return last.Add(delay).Sub(now)
+}
+
+func (g *LoginGuard) audit(userID *int, action string, client entity.ClientInfo, details string) {
+	entry := &entity.AuditLog{UserID: userID, Action: action, IP: client.IP, Details: details}
+	if err := g.auditRepo.Record(entry); err != nil {
+		log.Printf("failed to write audit log %q: %v", action, err)
+	}
+}
+
+// attemptTracker counts failures per key in memory
+type attemptTracker struct {
+	mu        sync.Mutex
+	window    time.Duration
+	records   map[string]*attemptRecord
+	lastSweep time.Time
+}
+
+type attemptRecord struct {
+	failures    int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+func newAttemptTracker(window time.Duration) *attemptTracker {
+	return &attemptTracker{window: window, records: make(map[string]*attemptRecord)}
+}
+
+// get returns a copy of the record for key, forgetting it if it went stale
+func (t *attemptTracker) get(key string, now time.Time) attemptRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sweep(now)
+	if record, ok := t.records[key]; ok {
+		return *record
+	}
+	return attemptRecord{}
+}
+
+// fail records a failure for key and returns the failure count
+func (t *attemptTracker) fail(key string, now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	record, ok := t.records[key]
+	if !ok {
+		record = &attemptRecord{}
+		t.records[key] = record
+	}
+	record.failures++
+	record.last = now
+	return record.failures
+}
+
+// lock blocks key until the given time; the key starts over with no failures
+// once the lock expires
+func (t *attemptTracker) lock(key string, until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	record, ok := t.records[key]
+	if !ok {
+		record = &attemptRecord{}
+		t.records[key] = record
+	}
+	record.failures = 0
+	record.lockedUntil = until
+}
+
+// sweep drops records that have been quiet for longer than the window.
+// Callers must hold t.mu.
+func (t *attemptTracker) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < time.Minute {
+		return
+	}
+	t.lastSweep = now
+	for key, record := range t.records {
+		if now.Sub(record.last) > t.window && now.After(record.lockedUntil) {
+			delete(t.records, key)
+		}
+	}
+}
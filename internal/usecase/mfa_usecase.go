@@ -0,0 +1,225 @@
+// internal/usecase/mfa_usecase.go
+package usecase
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+	"github.com/almatkai/book-exchange-backend/pkg/totp"
+	"github.com/almatkai/book-exchange-backend/pkg/utils"
+)
+
+var (
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled    = errors.New("two-factor authentication is not set up")
+	ErrInvalidMFACode     = errors.New("invalid two-factor code")
+)
+
+const (
+	recoveryCodeCount    = 10
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	qrCodeSize           = 256
+)
+
+// MFAUseCase manages TOTP two-factor authentication and recovery codes
+type MFAUseCase interface {
+	BeginTOTPEnrollment(user *entity.User) (*entity.TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(user *entity.User, code string) ([]string, error)
+	DisableTOTP(user *entity.User, password, code string, client entity.ClientInfo) error
+	RegenerateRecoveryCodes(user *entity.User, password, code string, client entity.ClientInfo) ([]string, error)
+	VerifySecondFactor(user *entity.User, code string) error
+}
+
+type mfaUseCase struct {
+	userRepo     repository.UserRepository
+	recoveryRepo repository.RecoveryCodeRepository
+	guard        *LoginGuard
+	issuer       string
+}
+
+func NewMFAUseCase(userRepo repository.UserRepository, recoveryRepo repository.RecoveryCodeRepository, guard *LoginGuard, issuer string) MFAUseCase {
+	return &mfaUseCase{
+		userRepo:     userRepo,
+		recoveryRepo: recoveryRepo,
+		guard:        guard,
+		issuer:       issuer,
+	}
+}
+
+// BeginTOTPEnrollment generates a new secret for the user. It is not enforced
+// until confirmed with a code from the authenticator app.
+func (uc *mfaUseCase) BeginTOTPEnrollment(user *entity.User) (*entity.TOTPEnrollment, error) {
+	if user.TOTPEnabled() {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.userRepo.SetTOTPSecret(user.UserID, secret); err != nil {
+		return nil, err
+	}
+
+	uri := totp.URI(uc.issuer, user.Username, secret)
+	png, err := totp.QRCodePNG(uri, qrCodeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.TOTPEnrollment{
+		Secret:    secret,
+		URI:       uri,
+		QRCodePNG: png,
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables two-factor authentication once the user proves
+// their app produces valid codes, and returns a fresh set of recovery codes
+func (uc *mfaUseCase) ConfirmTOTPEnrollment(user *entity.User, code string) ([]string, error) {
+	if user.TOTPEnabled() {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if err := uc.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	if err := uc.userRepo.EnableTOTP(user.UserID); err != nil {
+		return nil, err
+	}
+	return uc.newRecoveryCodes(user.UserID)
+}
+
+// DisableTOTP turns two-factor authentication off after checking the password
+// and a current code
+func (uc *mfaUseCase) DisableTOTP(user *entity.User, password, code string, client entity.ClientInfo) error {
+	if !user.TOTPEnabled() {
+		return ErrTOTPNotEnrolled
+	}
+	if err := uc.reauthenticate(user, password, code, client); err != nil {
+		return err
+	}
+
+	if err := uc.userRepo.DisableTOTP(user.UserID); err != nil {
+		return err
+	}
+	return uc.recoveryRepo.DeleteForUser(user.UserID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking the
+// password and a current code
+func (uc *mfaUseCase) RegenerateRecoveryCodes(user *entity.User, password, code string, client entity.ClientInfo) ([]string, error) {
+	if !user.TOTPEnabled() {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if err := uc.reauthenticate(user, password, code, client); err != nil {
+		return nil, err
+	}
+	return uc.newRecoveryCodes(user.UserID)
+}
+
+// reauthenticate checks both factors of a signed-in user before their
+// two-factor settings change. Wrong passwords and codes count towards the
+// lockouts like failed logins, so a stolen session cannot be used to guess codes.
+func (uc *mfaUseCase) reauthenticate(user *entity.User, password, code string, client entity.ClientInfo) error {
+	now := time.Now()
+	if err := uc.guard.checkPassword(user, password, client, now); err != nil {
+		return err
+	}
+	if err := uc.VerifySecondFactor(user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			uc.guard.recordFailure(user, client, now)
+		}
+		return err
+	}
+	uc.guard.recordSuccess(user)
+	return nil
+}
+
+// VerifySecondFactor accepts either a TOTP code or an unused recovery code
+func (uc *mfaUseCase) VerifySecondFactor(user *entity.User, code string) error {
+	if !user.TOTPEnabled() {
+		return ErrTOTPNotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits && isDigits(code) {
+		return uc.verifyTOTP(user, code)
+	}
+
+	err := uc.recoveryRepo.Consume(user.UserID, hashRecoveryCode(code))
+	if errors.Is(err, repository.ErrRecoveryCodeNotFound) {
+		return ErrInvalidMFACode
+	}
+	return err
+}
+
+func (uc *mfaUseCase) verifyTOTP(user *entity.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	// A code may only be used once, even within its validity window
+	err := uc.userRepo.RecordTOTPStep(user.UserID, step)
+	if errors.Is(err, repository.ErrTOTPCodeReused) {
+		return ErrInvalidMFACode
+	}
+	return err
+}
+
+func (uc *mfaUseCase) newRecoveryCodes(userID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = hashRecoveryCode(code)
+	}
+	if err := uc.recoveryRepo.ReplaceForUser(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code formatted as xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	var b strings.Builder
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			b.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// hashRecoveryCode normalizes user input so that case and separators don't matter
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return utils.HashToken(normalized)
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
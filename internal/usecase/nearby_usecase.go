@@ -0,0 +1,149 @@
+// internal/usecase/nearby_usecase.go
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+	"github.com/almatkai/book-exchange-backend/pkg/geo"
+)
+
+const (
+	defaultNearbyRadiusKm = 5
+	maxNearbyRadiusKm     = 100
+	// maxBoundingBoxKm bounds the diagonal of a searched box
+	maxBoundingBoxKm = 300
+)
+
+var (
+	ErrInvalidCoordinates  = errors.New("latitude must be between -90 and 90 and longitude between -180 and 180")
+	ErrInvalidRadius       = fmt.Errorf("radius_km must be greater than 0 and at most %d", maxNearbyRadiusKm)
+	ErrBoundingBoxTooLarge = fmt.Errorf("bounding box diagonal must be at most %d km", maxBoundingBoxKm)
+	ErrLocationRequired    = errors.New("pass lat and lng, or set your location first")
+)
+
+// NearbyUseCase finds available books and published posts around a position
+type NearbyUseCase interface {
+	NearbyBooks(user *entity.User, query entity.NearbyQuery) (*entity.Page[entity.NearbyBook], error)
+	NearbyPosts(user *entity.User, query entity.NearbyQuery) (*entity.Page[entity.NearbyPost], error)
+}
+
+type nearbyUseCase struct {
+	nearbyRepo repository.NearbyRepository
+}
+
+func NewNearbyUseCase(nearbyRepo repository.NearbyRepository) NearbyUseCase {
+	return &nearbyUseCase{nearbyRepo: nearbyRepo}
+}
+
+func (uc *nearbyUseCase) NearbyBooks(user *entity.User, query entity.NearbyQuery) (*entity.Page[entity.NearbyBook], error) {
+	filter, err := nearbyFilter(user, query)
+	if err != nil {
+		return nil, err
+	}
+	books, total, err := uc.nearbyRepo.NearbyBooks(*filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range books {
+		books[i].DistanceKm = roundDistance(books[i].DistanceKm)
+	}
+	return &entity.Page[entity.NearbyBook]{Items: books, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+func (uc *nearbyUseCase) NearbyPosts(user *entity.User, query entity.NearbyQuery) (*entity.Page[entity.NearbyPost], error) {
+	filter, err := nearbyFilter(user, query)
+	if err != nil {
+		return nil, err
+	}
+	posts, total, err := uc.nearbyRepo.NearbyPosts(*filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		posts[i].DistanceKm = roundDistance(posts[i].DistanceKm)
+	}
+	return &entity.Page[entity.NearbyPost]{Items: posts, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+// nearbyFilter validates a query and turns it into a box for the index plus
+// the exact radius. A box query sorts by distance from the center of the
+// box unless a center is given.
+func nearbyFilter(user *entity.User, query entity.NearbyQuery) (*repository.NearbyFilter, error) {
+	filter := &repository.NearbyFilter{ViewerID: user.UserID}
+	filter.Limit, filter.Offset = normalizePage(query.Limit, query.Offset)
+
+	center := query.Center
+	if center != nil {
+		if err := toPoint(*center).Validate(); err != nil {
+			return nil, ErrInvalidCoordinates
+		}
+	}
+
+	if query.Bounds != nil {
+		box := geo.Box{
+			MinLat: query.Bounds.MinLatitude,
+			MinLng: query.Bounds.MinLongitude,
+			MaxLat: query.Bounds.MaxLatitude,
+			MaxLng: query.Bounds.MaxLongitude,
+		}
+		if (geo.Point{Lat: box.MinLat, Lng: box.MinLng}).Validate() != nil ||
+			(geo.Point{Lat: box.MaxLat, Lng: box.MaxLng}).Validate() != nil || box.MinLat > box.MaxLat {
+			return nil, ErrInvalidCoordinates
+		}
+		if box.Diagonal() > maxBoundingBoxKm {
+			return nil, ErrBoundingBoxTooLarge
+		}
+		if center == nil {
+			middle := box.Center()
+			center = &entity.Coordinates{Latitude: middle.Lat, Longitude: middle.Lng}
+		}
+		filter.Center = *center
+		filter.Bounds = *query.Bounds
+		return filter, nil
+	}
+
+	if center == nil {
+		center = user.Coordinates()
+		if center == nil {
+			return nil, ErrLocationRequired
+		}
+	}
+	radius := query.RadiusKm
+	if radius == 0 {
+		radius = defaultNearbyRadiusKm
+	}
+	if math.IsNaN(radius) || radius < 0 || radius > maxNearbyRadiusKm {
+		return nil, ErrInvalidRadius
+	}
+
+	box := geo.BoundsAround(toPoint(*center), radius)
+	filter.Center = *center
+	filter.RadiusKm = radius
+	filter.Bounds = entity.BoundingBox{
+		MinLatitude:  box.MinLat,
+		MinLongitude: box.MinLng,
+		MaxLatitude:  box.MaxLat,
+		MaxLongitude: box.MaxLng,
+	}
+	return filter, nil
+}
+
+// approximateLocation snaps a position to a grid cell of about cellKm, the
+// precision at which it is shown to other users
+func approximateLocation(c entity.Coordinates, cellKm float64) entity.Coordinates {
+	p := geo.Fuzz(toPoint(c), cellKm)
+	return entity.Coordinates{Latitude: p.Lat, Longitude: p.Lng}
+}
+
+func toPoint(c entity.Coordinates) geo.Point {
+	return geo.Point{Lat: c.Latitude, Lng: c.Longitude}
+}
+
+// roundDistance rounds to 100 m; positions are fuzzed more than that anyway
+func roundDistance(km float64) float64 {
+	return math.Round(km*10) / 10
+}
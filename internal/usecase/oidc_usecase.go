@@ -0,0 +1,185 @@
+// internal/usecase/oidc_usecase.go
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+	"github.com/almatkai/book-exchange-backend/pkg/oidc"
+	"github.com/almatkai/book-exchange-backend/pkg/utils"
+)
+
+var (
+	ErrUnknownProvider     = errors.New("unknown identity provider")
+	ErrInvalidLoginState   = errors.New("invalid or expired login state")
+	ErrIdentityLinked      = repository.ErrIdentityLinked
+	ErrExternalLoginFailed = errors.New("identity provider login failed")
+)
+
+// OIDCUseCase drives the OpenID Connect authorization code flow with PKCE
+type OIDCUseCase interface {
+	Providers() []string
+	// BeginLogin returns the provider URL to send the browser to and the
+	// state, which the browser must present again at the callback. When
+	// linkUserID is set the callback links the identity to that user instead
+	// of logging in.
+	BeginLogin(ctx context.Context, provider string, linkUserID *int) (authURL, state string, err error)
+	// HandleCallback completes an authorization request. browserState is the
+	// state given to the browser that started it and must match state, so a
+	// request cannot be completed in another browser. It returns a login
+	// result for logins and the linked identity for link requests.
+	HandleCallback(ctx context.Context, provider, state, browserState, code string, client entity.ClientInfo) (*entity.LoginResult, *entity.ExternalIdentity, error)
+	ListIdentities(userID int) ([]entity.ExternalIdentity, error)
+}
+
+type oidcUseCase struct {
+	providers  map[string]*oidc.Provider
+	identities repository.IdentityRepository
+	users      UserUseCase
+	stateTTL   time.Duration
+}
+
+func NewOIDCUseCase(providers []*oidc.Provider, identities repository.IdentityRepository, users UserUseCase, stateTTL time.Duration) OIDCUseCase {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+	return &oidcUseCase{
+		providers:  byName,
+		identities: identities,
+		users:      users,
+		stateTTL:   stateTTL,
+	}
+}
+
+func (uc *oidcUseCase) Providers() []string {
+	names := make([]string, 0, len(uc.providers))
+	for name := range uc.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (uc *oidcUseCase) BeginLogin(ctx context.Context, providerName string, linkUserID *int) (string, string, error) {
+	provider, ok := uc.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrExternalLoginFailed, err)
+	}
+
+	if err := uc.identities.CreateState(&entity.OIDCLoginState{
+		StateHash:    utils.HashToken(state),
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		UserID:       linkUserID,
+		ExpiresAt:    time.Now().Add(uc.stateTTL),
+	}); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+func (uc *oidcUseCase) HandleCallback(ctx context.Context, providerName, state, browserState, code string, client entity.ClientInfo) (*entity.LoginResult, *entity.ExternalIdentity, error) {
+	provider, ok := uc.providers[providerName]
+	if !ok {
+		return nil, nil, ErrUnknownProvider
+	}
+	// Without this anyone could start a request and have it completed in a
+	// victim's browser, logging the victim in to the attacker's account or
+	// linking the victim's identity to it
+	if browserState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return nil, nil, ErrInvalidLoginState
+	}
+
+	pending, err := uc.identities.ConsumeState(utils.HashToken(state))
+	if err != nil {
+		if errors.Is(err, repository.ErrLoginStateNotFound) {
+			return nil, nil, ErrInvalidLoginState
+		}
+		return nil, nil, err
+	}
+	// A state issued for one provider must not be redeemed at another
+	if pending.Provider != providerName {
+		return nil, nil, ErrInvalidLoginState
+	}
+
+	tokens, err := provider.Exchange(ctx, code, pending.CodeVerifier)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrExternalLoginFailed, err)
+	}
+	idToken, err := provider.VerifyIDToken(ctx, tokens.IDToken, pending.Nonce)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrExternalLoginFailed, err)
+	}
+
+	profile := entity.ExternalProfile{
+		Provider:      providerName,
+		Subject:       idToken.Subject,
+		Email:         idToken.Email,
+		EmailVerified: idToken.EmailVerified,
+		Username:      idToken.PreferredUsername,
+		FullName:      idToken.Name,
+	}
+
+	if pending.UserID != nil {
+		identity, err := uc.link(*pending.UserID, profile)
+		return nil, identity, err
+	}
+	result, err := uc.users.LoginWithExternalIdentity(profile, client)
+	return result, nil, err
+}
+
+func (uc *oidcUseCase) ListIdentities(userID int) ([]entity.ExternalIdentity, error) {
+	return uc.identities.ListForUser(userID)
+}
+
+// link attaches an identity to a signed-in user. Linking the same identity
+// twice is a no-op; an identity owned by another user is refused.
+func (uc *oidcUseCase) link(userID int, profile entity.ExternalProfile) (*entity.ExternalIdentity, error) {
+	existing, err := uc.identities.Find(profile.Provider, profile.Subject)
+	if err == nil {
+		if existing.UserID != userID {
+			return nil, ErrIdentityLinked
+		}
+		return existing, nil
+	}
+	if !errors.Is(err, repository.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	identity := &entity.ExternalIdentity{
+		UserID:   userID,
+		Provider: profile.Provider,
+		Subject:  profile.Subject,
+		Email:    profile.Email,
+	}
+	if err := uc.identities.Create(identity); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
@@ -0,0 +1,82 @@
+// internal/usecase/privacy_usecase.go
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+	"github.com/almatkai/book-exchange-backend/pkg/storage"
+)
+
+// purgeBatchSize bounds the number of accounts erased per purge run
+const purgeBatchSize = 100
+
+// PrivacyUseCase exports and erases personal data
+type PrivacyUseCase interface {
+	ExportData(user *entity.User) (*entity.DataExport, error)
+	// PurgeDeletedAccounts erases accounts whose deletion grace period has
+	// ended and returns how many were erased. An account that cannot be
+	// erased is logged and skipped so it does not hold up the others.
+	PurgeDeletedAccounts() (int, error)
+}
+
+type privacyUseCase struct {
+	userRepo    repository.UserRepository
+	privacyRepo repository.PrivacyRepository
+	auditRepo   repository.AuditRepository
+	files       storage.Storage
+}
+
+func NewPrivacyUseCase(userRepo repository.UserRepository, privacyRepo repository.PrivacyRepository, auditRepo repository.AuditRepository, files storage.Storage) PrivacyUseCase {
+	return &privacyUseCase{
+		userRepo:    userRepo,
+		privacyRepo: privacyRepo,
+		auditRepo:   auditRepo,
+		files:       files,
+	}
+}
+
+func (uc *privacyUseCase) ExportData(user *entity.User) (*entity.DataExport, error) {
+	data, err := uc.privacyRepo.CollectUserData(user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.DataExport{
+		GeneratedAt: time.Now().UTC(),
+		Profile:     user,
+		Data:        data,
+	}, nil
+}
+
+func (uc *privacyUseCase) PurgeDeletedAccounts() (int, error) {
+	ids, err := uc.userRepo.ListDueForDeletion(time.Now(), purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		user, err := uc.userRepo.FindByID(id)
+		if err != nil {
+			log.Printf("failed to load user %d for deletion: %v", id, err)
+			continue
+		}
+		if err := uc.privacyRepo.AnonymizeAndDelete(id); err != nil {
+			log.Printf("failed to delete user %d: %v", id, err)
+			continue
+		}
+		purged++
+		removeAvatarFiles(context.Background(), uc.files, id, user.AvatarID, user.AvatarURLs)
+
+		// The audit entry cannot reference the deleted row
+		entry := &entity.AuditLog{Action: entity.AuditAccountDeleted, Details: fmt.Sprintf("user_id=%d", id)}
+		if err := uc.auditRepo.Record(entry); err != nil {
+			log.Printf("failed to write audit log %q: %v", entry.Action, err)
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,216 @@
+// internal/usecase/profile_usecase.go
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/almatkai/book-exchange-backend/internal/entity"
+	"github.com/almatkai/book-exchange-backend/internal/repository"
+	"github.com/almatkai/book-exchange-backend/pkg/imaging"
+	"github.com/almatkai/book-exchange-backend/pkg/storage"
+)
+
+var (
+	ErrInvalidFullName    = errors.New("full_name must be at most 100 characters on a single line")
+	ErrInvalidLocation    = errors.New("location must be at most 255 characters on a single line")
+	ErrInvalidBio         = errors.New("bio must be at most 1000 characters")
+	ErrEmptyProfileUpdate = errors.New("no profile fields to update")
+	ErrInvalidImage       = errors.New("file is not a supported image (JPEG, PNG, GIF or WebP)")
+	ErrImageTooLarge      = errors.New("image dimensions are too large")
+)
+
+// Profile field limits, in characters
+const (
+	maxFullNameLength = 100
+	maxLocationLength = 255
+	maxBioLength      = 1000
+)
+
+const (
+	// maxAvatarPixels bounds the decoded size of an uploaded avatar
+	maxAvatarPixels = 25_000_000
+	avatarQuality   = 85
+)
+
+// ProfileUseCase reads and edits user profiles
+type ProfileUseCase interface {
+	UpdateProfile(user *entity.User, update entity.UpdateProfileRequest) (*entity.User, error)
+	GetPublicProfile(username string) (*entity.PublicProfile, error)
+	UploadAvatar(ctx context.Context, user *entity.User, data []byte) (*entity.User, error)
+	DeleteAvatar(ctx context.Context, user *entity.User) error
+	SetLocation(user *entity.User, location entity.Coordinates) (*entity.User, error)
+	ClearLocation(user *entity.User) error
+}
+
+// ProfileSettings configures avatar processing and location privacy
+type ProfileSettings struct {
+	// AvatarSizes are the edge lengths, in pixels, of the square thumbnails
+	// generated for every avatar
+	AvatarSizes []int
+	// LocationPrecisionKm is the size of the grid cells user positions are
+	// snapped to before other users can see them
+	LocationPrecisionKm float64
+}
+
+type profileUseCase struct {
+	userRepo repository.UserRepository
+	files    storage.Storage
+	settings ProfileSettings
+}
+
+func NewProfileUseCase(userRepo repository.UserRepository, files storage.Storage, settings ProfileSettings) ProfileUseCase {
+	return &profileUseCase{
+		userRepo: userRepo,
+		files:    files,
+		settings: settings,
+	}
+}
+
+// UpdateProfile validates and stores the fields set in update and returns
+// the updated user
+func (uc *profileUseCase) UpdateProfile(user *entity.User, update entity.UpdateProfileRequest) (*entity.User, error) {
+	if update.FullName == nil && update.Location == nil && update.Bio == nil {
+		return nil, ErrEmptyProfileUpdate
+	}
+
+	var err error
+	if update.FullName, err = cleanTextField(update.FullName, maxFullNameLength, false, ErrInvalidFullName); err != nil {
+		return nil, err
+	}
+	if update.Location, err = cleanTextField(update.Location, maxLocationLength, false, ErrInvalidLocation); err != nil {
+		return nil, err
+	}
+	if update.Bio, err = cleanTextField(update.Bio, maxBioLength, true, ErrInvalidBio); err != nil {
+		return nil, err
+	}
+
+	if err := uc.userRepo.UpdateProfile(user.UserID, update); err != nil {
+		return nil, err
+	}
+	return uc.userRepo.FindByID(user.UserID)
+}
+
+// GetPublicProfile returns the public view of an active account
+func (uc *profileUseCase) GetPublicProfile(username string) (*entity.PublicProfile, error) {
+	user, err := uc.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	return user.PublicProfile(), nil
+}
+
+// UploadAvatar replaces the user's avatar with square JPEG thumbnails of the
+// image. Re-encoding drops EXIF and other metadata, such as GPS positions.
+func (uc *profileUseCase) UploadAvatar(ctx context.Context, user *entity.User, data []byte) (*entity.User, error) {
+	img, err := imaging.Decode(data, maxAvatarPixels)
+	if err != nil {
+		if errors.Is(err, imaging.ErrTooManyPixels) {
+			return nil, ErrImageTooLarge
+		}
+		return nil, ErrInvalidImage
+	}
+
+	avatarID := uuid.NewString()
+	urls := entity.AvatarURLs{}
+	for _, size := range uc.settings.AvatarSizes {
+		key := avatarKey(user.UserID, avatarID, strconv.Itoa(size))
+		if err := uc.storeThumbnail(ctx, img, size, key); err != nil {
+			removeAvatarFiles(ctx, uc.files, user.UserID, &avatarID, urls)
+			return nil, fmt.Errorf("store avatar: %w", err)
+		}
+		urls[strconv.Itoa(size)] = uc.files.URL(key)
+	}
+
+	if err := uc.userRepo.SetAvatar(user.UserID, &avatarID, urls); err != nil {
+		removeAvatarFiles(ctx, uc.files, user.UserID, &avatarID, urls)
+		return nil, err
+	}
+	removeAvatarFiles(ctx, uc.files, user.UserID, user.AvatarID, user.AvatarURLs)
+	return uc.userRepo.FindByID(user.UserID)
+}
+
+// DeleteAvatar removes the user's avatar
+func (uc *profileUseCase) DeleteAvatar(ctx context.Context, user *entity.User) error {
+	if user.AvatarID == nil {
+		return nil
+	}
+	if err := uc.userRepo.SetAvatar(user.UserID, nil, nil); err != nil {
+		return err
+	}
+	removeAvatarFiles(ctx, uc.files, user.UserID, user.AvatarID, user.AvatarURLs)
+	return nil
+}
+
+// SetLocation stores the user's exact position and places their books and
+// posts at its approximate version
+func (uc *profileUseCase) SetLocation(user *entity.User, location entity.Coordinates) (*entity.User, error) {
+	if err := toPoint(location).Validate(); err != nil {
+		return nil, ErrInvalidCoordinates
+	}
+	approx := approximateLocation(location, uc.settings.LocationPrecisionKm)
+	if err := uc.userRepo.SetLocation(user.UserID, &location, &approx); err != nil {
+		return nil, err
+	}
+	return uc.userRepo.FindByID(user.UserID)
+}
+
+// ClearLocation forgets the user's position; their listings no longer show
+// up in nearby searches
+func (uc *profileUseCase) ClearLocation(user *entity.User) error {
+	return uc.userRepo.SetLocation(user.UserID, nil, nil)
+}
+
+func (uc *profileUseCase) storeThumbnail(ctx context.Context, img *imaging.Image, size int, key string) error {
+	thumb, err := imaging.EncodeJPEG(imaging.SquareThumbnail(img, size), avatarQuality)
+	if err != nil {
+		return err
+	}
+	return uc.files.Put(ctx, key, thumb, "image/jpeg")
+}
+
+func avatarKey(userID int, avatarID, size string) string {
+	return fmt.Sprintf("avatars/%d/%s/%s.jpg", userID, avatarID, size)
+}
+
+// removeAvatarFiles deletes the thumbnails of an avatar that is no longer
+// referenced. Failures only leave unreachable files behind, so they are logged.
+func removeAvatarFiles(ctx context.Context, files storage.Storage, userID int, avatarID *string, urls entity.AvatarURLs) {
+	if avatarID == nil {
+		return
+	}
+	for size := range urls {
+		if err := files.Delete(ctx, avatarKey(userID, *avatarID, size)); err != nil {
+			log.Printf("failed to delete avatar file of user %d: %v", userID, err)
+		}
+	}
+}
+
+// cleanTextField trims a submitted text field and checks its length and
+// characters. Line breaks are only accepted when multiline is set.
+func cleanTextField(value *string, maxLength int, multiline bool, invalid error) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	cleaned := strings.TrimSpace(*value)
+	if !utf8.ValidString(cleaned) || utf8.RuneCountInString(cleaned) > maxLength {
+		return nil, invalid
+	}
+	for _, r := range cleaned {
+		if multiline && (r == '\n' || r == '\r' || r == '\t') {
+			continue
+		}
+		if unicode.IsControl(r) {
+			return nil, invalid
+		}
+	}
+	return &cleaned, nil
+}
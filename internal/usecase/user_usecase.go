@@ -4,6 +4,13 @@ package usecase
 import (
 	"errors"
 	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
 
 	"github.com/almatkai/book-exchange-backend/internal/entity"
 	"github.com/almatkai/book-exchange-backend/internal/repository"
@@ -11,71 +18,708 @@ import (
 )
 
 var (
-	ErrUsernameExists     = repository.ErrUsernameExists
-	ErrEmailExists        = repository.ErrEmailExists
-	ErrInvalidCredentials = repository.ErrInvalidCredentials
+	ErrUsernameExists        = repository.ErrUsernameExists
+	ErrEmailExists           = repository.ErrEmailExists
+	ErrInvalidCredentials    = repository.ErrInvalidCredentials
+	ErrInvalidRefreshToken   = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused    = repository.ErrRefreshTokenReused
+	ErrInvalidMFAToken       = errors.New("invalid or expired mfa token")
+	ErrAccountDisabled       = errors.New("account is disabled")
+	ErrSessionNotFound       = repository.ErrSessionNotFound
+	ErrWrongPassword         = errors.New("current password is incorrect")
+	ErrAccountNotDeactivated = errors.New("account is not deactivated")
+	// ErrExternalEmailRequired means the provider did not share an email address
+	ErrExternalEmailRequired = errors.New("identity provider did not share an email address")
+	// ErrExternalEmailConflict means a local account already uses the email
+	// address and cannot be linked automatically
+	ErrExternalEmailConflict = errors.New("an account with this email already exists, sign in and link the provider instead")
 )
 
+// usernameAttempts bounds retries when a generated username is already taken
+const usernameAttempts = 5
+
 type UserUseCase interface {
 	RegisterUser(username, email, password string) (*entity.User, error)
-	LoginUser(username, password string) (string, error) // Returns JWT token on success
+	LoginUser(login, password string, client entity.ClientInfo) (*entity.LoginResult, error)
+	CompleteMFALogin(mfaToken, code string, client entity.ClientInfo) (*entity.TokenPair, error)
+	RefreshTokens(refreshToken string) (*entity.TokenPair, error)
+	Logout(claims *utils.Claims, refreshToken string) error
+	LogoutAll(userID int) error
+	LoginWithExternalIdentity(profile entity.ExternalProfile, client entity.ClientInfo) (*entity.LoginResult, error)
+	ListSessions(userID int, currentSessionID string) ([]entity.Session, error)
+	RevokeSession(userID int, sessionID uuid.UUID) error
+	ChangePassword(user *entity.User, currentPassword, newPassword string, client entity.ClientInfo) (*entity.TokenPair, error)
+	DeactivateAccount(user *entity.User, password string, client entity.ClientInfo) error
+	ScheduleAccountDeletion(user *entity.User, password string, client entity.ClientInfo) (time.Time, error)
+	ReactivateAccount(login, password string, client entity.ClientInfo) (*entity.LoginResult, error)
+}
+
+// UserSettings tunes token lifetimes of the login flow
+type UserSettings struct {
+	RefreshTokenTTL time.Duration
+	// MFATokenTTL bounds the time between the password and second factor steps
+	MFATokenTTL time.Duration
+	// DeletionGracePeriod is how long a deleted account can still be reactivated
+	DeletionGracePeriod time.Duration
 }
 
 type userUseCase struct {
-	userRepo repository.UserRepository
-	jwtSvc   utils.JWTService
+	userRepo    repository.UserRepository
+	refreshRepo repository.RefreshTokenRepository
+	revokedRepo repository.RevokedTokenRepository
+	roleRepo    repository.RoleRepository
+	identities  repository.IdentityRepository
+	sessionRepo repository.SessionRepository
+	auditRepo   repository.AuditRepository
+	accounts    AccountUseCase
+	mfa         MFAUseCase
+	jwtSvc      utils.JWTService
+	hasher      utils.PasswordHasher
+	settings    UserSettings
+	guard       *LoginGuard
 }
 
-func NewUserUseCase(userRepo repository.UserRepository, jwtSvc utils.JWTService) UserUseCase {
+func NewUserUseCase(userRepo repository.UserRepository, refreshRepo repository.RefreshTokenRepository, revokedRepo repository.RevokedTokenRepository, roleRepo repository.RoleRepository, identities repository.IdentityRepository, sessionRepo repository.SessionRepository, auditRepo repository.AuditRepository, accounts AccountUseCase, mfa MFAUseCase, jwtSvc utils.JWTService, hasher utils.PasswordHasher, guard *LoginGuard, settings UserSettings) UserUseCase {
 	return &userUseCase{
-		userRepo: userRepo,
-		jwtSvc:   jwtSvc,
+		userRepo:    userRepo,
+		refreshRepo: refreshRepo,
+		revokedRepo: revokedRepo,
+		roleRepo:    roleRepo,
+		identities:  identities,
+		sessionRepo: sessionRepo,
+		auditRepo:   auditRepo,
+		accounts:    accounts,
+		mfa:         mfa,
+		jwtSvc:      jwtSvc,
+		hasher:      hasher,
+		settings:    settings,
+		guard:       guard,
 	}
 }
 
 func (uc *userUseCase) RegisterUser(username, email, password string) (*entity.User, error) {
+	hash, err := uc.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create new user entity
 	user := &entity.User{
 		Username: username,
 		Email:    email,
-		Password: password, // Plain password; will be hashed in repository
+		Password: hash,
 		// Initialize other fields as needed
 		IsActive: true,
 	}
 
-	// Create user in repository (this will handle hashing and uniqueness)
-	if err := uc.userRepo.Create(user); err != nil {
+	// Create user in repository (this will handle uniqueness)
+	if err := uc.createAccount(user); err != nil {
 		return nil, err
 	}
 
+	uc.sendEmailVerification(user)
 	return user, nil
 }
 
-func (uc *userUseCase) LoginUser(username, password string) (string, error) {
-	user, err := uc.userRepo.FindByUsername(username)
+// createAccount stores a new user with the default role. Emails are stored
+// lowercase.
+func (uc *userUseCase) createAccount(user *entity.User) error {
+	user.Email = strings.ToLower(strings.TrimSpace(user.Email))
+	return uc.userRepo.Create(user, entity.RoleUser)
+}
+
+func (uc *userUseCase) sendEmailVerification(user *entity.User) {
+	// The account exists either way; the user can ask for another email
+	if err := uc.accounts.SendEmailVerification(user); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", user.UserID, err)
+	}
+}
+
+// LoginUser authenticates with a username or email address, ignoring case
+func (uc *userUseCase) LoginUser(login, password string, client entity.ClientInfo) (*entity.LoginResult, error) {
+	now := time.Now()
+	if err := uc.guard.checkIP(client.IP, now); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.FindByLogin(login)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
-			return "", ErrInvalidCredentials
+			uc.guard.recordFailure(nil, client, now)
+			return nil, ErrInvalidCredentials
 		}
-		return "", err
+		return nil, err
+	}
+	if err := uc.guard.reserveAttempt(user, now); err != nil {
+		return nil, err
 	}
 
 	// Compare password
-	if err := utils.CheckPasswordHash(password, user.Password); err != nil {
-		return "", ErrInvalidCredentials
+	if err := uc.hasher.Verify(password, user.Password); err != nil {
+		uc.guard.recordFailure(user, client, now)
+		return nil, ErrInvalidCredentials
+	}
+	uc.guard.recordFirstFactor(user)
+	uc.upgradePasswordHash(user, password)
+
+	return uc.completeFirstFactor(user, client)
+}
+
+// upgradePasswordHash rehashes a just verified password when the hashing
+// algorithm or its cost has changed since the hash was created
+func (uc *userUseCase) upgradePasswordHash(user *entity.User, password string) {
+	if !uc.hasher.NeedsRehash(user.Password) {
+		return
+	}
+	hash, err := uc.hasher.Hash(password)
+	if err != nil {
+		log.Printf("failed to rehash password of user %d: %v", user.UserID, err)
+		return
+	}
+	// The old hash still works, so a failure here only delays the upgrade
+	if err := uc.userRepo.UpdatePassword(user.UserID, hash); err != nil {
+		log.Printf("failed to store rehashed password of user %d: %v", user.UserID, err)
+		return
+	}
+	user.Password = hash
+}
+
+// ChangePassword replaces the password of a signed-in user. Every session,
+// including the current one, is ended and the caller receives tokens for a
+// new session, so that anyone who knew the old password is logged out.
+func (uc *userUseCase) ChangePassword(user *entity.User, currentPassword, newPassword string, client entity.ClientInfo) (*entity.TokenPair, error) {
+	if err := uc.confirmPassword(user, currentPassword, client); err != nil {
+		return nil, err
+	}
+
+	hash, err := uc.hasher.Hash(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.userRepo.UpdatePassword(user.UserID, hash); err != nil {
+		return nil, err
+	}
+
+	if err := uc.LogoutAll(user.UserID); err != nil {
+		return nil, err
+	}
+	// Reload for the bumped token version
+	updated, err := uc.userRepo.FindByID(user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.startSession(updated, client)
+}
+
+// DeactivateAccount disables the account until its owner reactivates it
+func (uc *userUseCase) DeactivateAccount(user *entity.User, password string, client entity.ClientInfo) error {
+	if err := uc.confirmPassword(user, password, client); err != nil {
+		return err
+	}
+	if err := uc.userRepo.Deactivate(user.UserID, time.Now(), nil); err != nil {
+		return err
+	}
+	if err := uc.LogoutAll(user.UserID); err != nil {
+		return err
+	}
+	uc.audit(user.UserID, entity.AuditAccountDeactivated, client, "")
+	return nil
+}
+
+// ScheduleAccountDeletion deactivates the account and schedules it to be
+// anonymized and deleted once the grace period has passed. Reactivating the
+// account before then cancels the deletion.
+func (uc *userUseCase) ScheduleAccountDeletion(user *entity.User, password string, client entity.ClientInfo) (time.Time, error) {
+	if err := uc.confirmPassword(user, password, client); err != nil {
+		return time.Time{}, err
+	}
+	now := time.Now()
+	deleteAt := now.Add(uc.settings.DeletionGracePeriod)
+	if err := uc.userRepo.Deactivate(user.UserID, now, &deleteAt); err != nil {
+		return time.Time{}, err
+	}
+	if err := uc.LogoutAll(user.UserID); err != nil {
+		return time.Time{}, err
+	}
+	uc.audit(user.UserID, entity.AuditAccountDeletionScheduled, client, "delete_at="+deleteAt.Format(time.RFC3339))
+	return deleteAt, nil
+}
+
+// ReactivateAccount signs in to a self-deactivated account, re-enabling it
+// and cancelling any scheduled deletion. Suspended accounts stay disabled.
+func (uc *userUseCase) ReactivateAccount(login, password string, client entity.ClientInfo) (*entity.LoginResult, error) {
+	now := time.Now()
+	if err := uc.guard.checkIP(client.IP, now); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.FindByLoginIncludingInactive(login)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			uc.guard.recordFailure(nil, client, now)
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if err := uc.guard.reserveAttempt(user, now); err != nil {
+		return nil, err
+	}
+	if err := uc.hasher.Verify(password, user.Password); err != nil {
+		uc.guard.recordFailure(user, client, now)
+		return nil, ErrInvalidCredentials
+	}
+	uc.guard.recordFirstFactor(user)
+
+	if user.IsActive {
+		return nil, ErrAccountNotDeactivated
+	}
+	if !user.SelfDeactivated() {
+		return nil, ErrAccountDisabled
+	}
+	if err := uc.userRepo.Reactivate(user.UserID); err != nil {
+		return nil, err
+	}
+	uc.audit(user.UserID, entity.AuditAccountReactivated, client, "")
+
+	user.IsActive = true
+	user.DeactivatedAt = nil
+	user.DeletionScheduledAt = nil
+	return uc.completeFirstFactor(user, client)
+}
+
+// confirmPassword re-authenticates a signed-in user before a sensitive
+// change. Wrong passwords count towards the account lockout like failed logins.
+func (uc *userUseCase) confirmPassword(user *entity.User, password string, client entity.ClientInfo) error {
+	if err := uc.guard.checkPassword(user, password, client, time.Now()); err != nil {
+		return err
+	}
+	uc.guard.recordSuccess(user)
+	return nil
+}
+
+func (uc *userUseCase) audit(userID int, action string, client entity.ClientInfo, details string) {
+	entry := &entity.AuditLog{UserID: &userID, Action: action, IP: client.IP, Details: details}
+	if err := uc.auditRepo.Record(entry); err != nil {
+		log.Printf("failed to write audit log %q: %v", action, err)
+	}
+}
+
+// completeFirstFactor starts a session, or for accounts with two-factor
+// authentication issues a token for the second step only
+func (uc *userUseCase) completeFirstFactor(user *entity.User, client entity.ClientInfo) (*entity.LoginResult, error) {
+	if user.TOTPEnabled() {
+		mfaToken, err := uc.jwtSvc.GenerateMFAToken(user.UserID, uc.settings.MFATokenTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &entity.LoginResult{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+
+	tokens, err := uc.startSession(user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.LoginResult{TokenPair: tokens}, nil
+}
+
+// LoginWithExternalIdentity signs in the user linked to an identity asserted
+// by an OpenID Connect provider. On first login the identity is linked to the
+// account with the same email address when both sides have verified it, and
+// otherwise a new account is created.
+func (uc *userUseCase) LoginWithExternalIdentity(profile entity.ExternalProfile, client entity.ClientInfo) (*entity.LoginResult, error) {
+	identity, err := uc.identities.Find(profile.Provider, profile.Subject)
+	var user *entity.User
+	switch {
+	case err == nil:
+		user, err = uc.userRepo.FindByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+	case errors.Is(err, repository.ErrIdentityNotFound):
+		user, identity, err = uc.linkOrCreateAccount(profile)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, ErrAccountDisabled
+	}
+	if err := uc.identities.TouchLogin(identity.ID); err != nil {
+		log.Printf("failed to record login for identity %d: %v", identity.ID, err)
+	}
+	return uc.completeFirstFactor(user, client)
+}
+
+func (uc *userUseCase) linkOrCreateAccount(profile entity.ExternalProfile) (*entity.User, *entity.ExternalIdentity, error) {
+	if profile.Email == "" {
+		return nil, nil, ErrExternalEmailRequired
+	}
+
+	user, err := uc.userRepo.FindByEmail(profile.Email)
+	switch {
+	case err == nil:
+		// Anyone can register an unverified address at some providers, so
+		// only link when both sides have proven ownership of the mailbox
+		if !profile.EmailVerified || !user.EmailVerified() {
+			return nil, nil, ErrExternalEmailConflict
+		}
+	case errors.Is(err, repository.ErrUserNotFound):
+		user, err = uc.createExternalAccount(profile)
+		if err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, err
+	}
+
+	identity := &entity.ExternalIdentity{
+		UserID:   user.UserID,
+		Provider: profile.Provider,
+		Subject:  profile.Subject,
+		Email:    profile.Email,
+	}
+	if err := uc.identities.Create(identity); err != nil {
+		return nil, nil, err
+	}
+	return user, identity, nil
+}
+
+// createExternalAccount creates a user for a first-time external login. The
+// account gets a random password; a local password can be set with the
+// password reset flow.
+func (uc *userUseCase) createExternalAccount(profile entity.ExternalProfile) (*entity.User, error) {
+	password, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := uc.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	base := usernameBase(profile)
+	for attempt := 0; attempt < usernameAttempts; attempt++ {
+		username := base
+		if attempt > 0 {
+			username = fmt.Sprintf("%s_%04d", base, rand.Intn(10000))
+		}
+		user := &entity.User{
+			Username: username,
+			Email:    profile.Email,
+			Password: hash,
+			FullName: profile.FullName,
+			IsActive: true,
+		}
+		err := uc.createAccount(user)
+		if errors.Is(err, ErrUsernameExists) {
+			continue
+		}
+		if errors.Is(err, ErrEmailExists) {
+			return nil, ErrExternalEmailConflict
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if profile.EmailVerified {
+			if err := uc.userRepo.MarkEmailVerified(user.UserID); err != nil {
+				return nil, err
+			}
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		} else {
+			uc.sendEmailVerification(user)
+		}
+		return user, nil
+	}
+	return nil, ErrUsernameExists
+}
+
+// usernameBase derives a username from the provider's preferred username or
+// the local part of the email address
+func usernameBase(profile entity.ExternalProfile) string {
+	candidate := profile.Username
+	if candidate == "" {
+		candidate, _, _ = strings.Cut(profile.Email, "@")
+	}
+
+	var b strings.Builder
+	for _, r := range candidate {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-') {
+			b.WriteRune(r)
+		}
+	}
+	username := b.String()
+	if len(username) > 40 {
+		username = username[:40]
+	}
+	if len(username) < 3 {
+		username = "user" + username
+	}
+	return username
+}
+
+// CompleteMFALogin finishes a two-factor login. An mfa token is good for a
+// single attempt: after a wrong code the user has to enter their password
+// again. Wrong codes count towards the lockouts like wrong passwords.
+func (uc *userUseCase) CompleteMFALogin(mfaToken, code string, client entity.ClientInfo) (*entity.TokenPair, error) {
+	now := time.Now()
+	if err := uc.guard.checkIP(client.IP, now); err != nil {
+		return nil, err
 	}
 
+	claims, err := uc.jwtSvc.ValidateMFAToken(mfaToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	revoked, err := uc.revokedRepo.IsRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidMFAToken
+	}
+	if err := uc.revokedRepo.Revoke(&entity.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrInvalidMFAToken
+	}
+	// The attempt was reserved when the password was checked, and the mfa
+	// token allows no other
+	if user.IsLocked(now) {
+		return nil, ErrAccountLocked
+	}
+
+	if err := uc.mfa.VerifySecondFactor(user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			uc.guard.recordFailure(user, client, now)
+		}
+		return nil, err
+	}
+	uc.guard.recordSuccess(user)
+	return uc.startSession(user, client)
+}
+
+// startSession records the login and issues tokens for a new session. The
+// session ID doubles as the family ID of its refresh tokens.
+func (uc *userUseCase) startSession(user *entity.User, client entity.ClientInfo) (*entity.TokenPair, error) {
 	// Update last login
 	if err := uc.userRepo.UpdateLastLogin(user.UserID); err != nil {
 		// Log the error but don't prevent login
-		fmt.Printf("Error updating last login: %v\n", err)
+		log.Printf("failed to update last login of user %d: %v", user.UserID, err)
+	}
+
+	session := &entity.Session{
+		ID:         uuid.New(),
+		UserID:     user.UserID,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		LastSeenAt: time.Now(),
+	}
+	if err := uc.sessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := uc.newRefreshToken(user.UserID, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.refreshRepo.Create(refreshToken.record); err != nil {
+		return nil, err
+	}
+
+	return uc.tokenPair(user, refreshToken.raw, session.ID)
+}
+
+// RefreshTokens exchanges a valid refresh token for a new token pair. The
+// presented token is consumed; presenting it again is treated as theft and
+// revokes every token in its family.
+func (uc *userUseCase) RefreshTokens(refreshToken string) (*entity.TokenPair, error) {
+	current, err := uc.refreshRepo.FindByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if current.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if current.UsedAt != nil {
+		return nil, uc.revokeReusedFamily(current)
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := uc.userRepo.FindByID(current.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	next, err := uc.newRefreshToken(user.UserID, current.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.refreshRepo.Rotate(current, next.record); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenReused) {
+			// Lost a race against another request presenting the same token
+			return nil, uc.revokeReusedFamily(current)
+		}
+		return nil, err
+	}
+
+	return uc.tokenPair(user, next.raw, current.FamilyID)
+}
+
+// Logout revokes the access token described by claims and ends its session.
+// Tokens issued before sessions were tracked carry no session, so the refresh
+// token the client holds is revoked as well when given.
+func (uc *userUseCase) Logout(claims *utils.Claims, refreshToken string) error {
+	if err := uc.revokedRepo.Revoke(&entity.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}); err != nil {
+		return err
+	}
+
+	if sessionID, err := uuid.Parse(claims.SessionID); err == nil {
+		if err := uc.refreshRepo.RevokeFamily(sessionID); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	token, err := uc.refreshRepo.FindByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil
+		}
+		return err
+	}
+	// Never let one user revoke another user's tokens
+	if token.UserID != claims.UserID {
+		return nil
+	}
+	return uc.refreshRepo.RevokeFamily(token.FamilyID)
+}
+
+// LogoutAll ends every session of a user by bumping the token version, which
+// invalidates all access tokens, and revoking all refresh tokens
+func (uc *userUseCase) LogoutAll(userID int) error {
+	if err := uc.userRepo.IncrementTokenVersion(userID); err != nil {
+		return err
+	}
+	return uc.refreshRepo.RevokeAllForUser(userID)
+}
+
+// ListSessions returns the user's sessions that can still be refreshed,
+// flagging the one identified by currentSessionID
+func (uc *userUseCase) ListSessions(userID int, currentSessionID string) ([]entity.Session, error) {
+	sessions, err := uc.sessionRepo.ListActiveForUser(userID, time.Now().Add(-uc.settings.RefreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID.String() == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions. Its refresh tokens stop
+// working immediately and its access tokens are rejected by the auth middleware.
+func (uc *userUseCase) RevokeSession(userID int, sessionID uuid.UUID) error {
+	session, err := uc.sessionRepo.FindByID(sessionID)
+	if err != nil {
+		return err
+	}
+	// Do not reveal other users' sessions
+	if session.UserID != userID || session.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+	return uc.refreshRepo.RevokeFamily(sessionID)
+}
+
+func (uc *userUseCase) revokeReusedFamily(token *entity.RefreshToken) error {
+	if err := uc.refreshRepo.RevokeFamily(token.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+type issuedRefreshToken struct {
+	raw    string
+	record *entity.RefreshToken
+}
+
+func (uc *userUseCase) newRefreshToken(userID int, familyID uuid.UUID) (*issuedRefreshToken, error) {
+	raw, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	return &issuedRefreshToken{
+		raw: raw,
+		record: &entity.RefreshToken{
+			UserID:    userID,
+			FamilyID:  familyID,
+			TokenHash: utils.HashToken(raw),
+			ExpiresAt: time.Now().Add(uc.settings.RefreshTokenTTL),
+		},
+	}, nil
+}
+
+func (uc *userUseCase) tokenPair(user *entity.User, refreshToken string, sessionID uuid.UUID) (*entity.TokenPair, error) {
+	roles, err := uc.roleRepo.RolesForUser(user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := uc.roleRepo.PermissionsForUser(user.UserID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Generate JWT token
-	token, err := uc.jwtSvc.GenerateToken(fmt.Sprintf("%d", user.UserID))
+	accessToken, err := uc.jwtSvc.GenerateToken(utils.Claims{
+		UserID:       user.UserID,
+		TokenVersion: user.TokenVersion,
+		Roles:        roles,
+		Permissions:  permissions,
+		SessionID:    sessionID.String(),
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return token, nil
+	return &entity.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(uc.jwtSvc.TokenTTL().Seconds()),
+	}, nil
 }
@@ -5,13 +5,19 @@ import (
 	"github.com/almatkai/book-exchange-backend/internal/config"
 	"github.com/almatkai/book-exchange-backend/internal/delivery/router"
 	"github.com/almatkai/book-exchange-backend/internal/delivery/router/handlers"
+	"github.com/almatkai/book-exchange-backend/internal/middleware"
 	"github.com/almatkai/book-exchange-backend/internal/repository"
 	"github.com/almatkai/book-exchange-backend/internal/usecase"
+	"github.com/almatkai/book-exchange-backend/pkg/bookmeta"
+	"github.com/almatkai/book-exchange-backend/pkg/mailer"
+	"github.com/almatkai/book-exchange-backend/pkg/oidc"
+	"github.com/almatkai/book-exchange-backend/pkg/storage"
 	"github.com/almatkai/book-exchange-backend/pkg/utils"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -23,17 +29,23 @@ func main() {
 	}
 
 	// Load config
-	cfg := config.LoadConfig()
-
-	jwtService := utils.InitJWT(cfg.JWTSecret)
-	jwtKey := []byte(cfg.JWTSecret)
-
-	log.Print(jwtService)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
 
-	// Optionally, log if JWT secret is set correctly
-	if len(cfg.JWTSecret) == 0 {
+	var jwtService utils.JWTService
+	if cfg.JWTActiveKey.ID != "" {
+		keys, err := loadJWTKeys(cfg)
+		if err != nil {
+			log.Fatalf("failed to load JWT signing keys: %v", err)
+		}
+		jwtService = utils.InitJWTWithKeys(keys, cfg.AccessTokenTTL)
+		log.Printf("JWT signing key %q loaded (%s)", cfg.JWTActiveKey.ID, keys.Active().Method.Alg())
+	} else if len(cfg.JWTSecret) == 0 {
 		log.Fatal("JWT_SECRET is not set in the environment")
 	} else {
+		jwtService = utils.InitJWT(cfg.JWTSecret, cfg.AccessTokenTTL)
 		log.Println("JWT Secret initialized successfully")
 	}
 
@@ -44,16 +56,188 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
+	if err := repository.RegisterBlockGuard(db); err != nil {
+		log.Fatalf("failed to register block guard: %v", err)
+	}
+
+	// Outgoing mail
+	mail, err := mailer.NewLocalMailer(cfg.MailDir, cfg.MailFrom)
+	if err != nil {
+		log.Fatalf("failed to initialize mailer: %v", err)
+	}
+
+	passwordHasher, err := utils.NewPasswordHasher(utils.PasswordHashConfig{
+		Algorithm:         cfg.PasswordHashAlgorithm,
+		BcryptCost:        cfg.BcryptCost,
+		Argon2Memory:      uint32(cfg.Argon2Memory),
+		Argon2Iterations:  uint32(cfg.Argon2Iterations),
+		Argon2Parallelism: uint8(cfg.Argon2Parallelism),
+	})
+	if err != nil {
+		log.Fatalf("invalid password hashing configuration: %v", err)
+	}
+
+	// Uploaded files
+	var files storage.Storage
+	var media http.Handler
+	switch cfg.StorageBackend {
+	case "local":
+		local, err := storage.NewLocalStorage(cfg.StorageLocalDir, cfg.AppBaseURL+"/media")
+		if err != nil {
+			log.Fatalf("failed to initialize file storage: %v", err)
+		}
+		files, media = local, local.Handler()
+	case "s3":
+		files, err = storage.NewS3Storage(storage.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretKey,
+			PublicURL:       cfg.S3PublicURL,
+		}, nil)
+		if err != nil {
+			log.Fatalf("failed to initialize file storage: %v", err)
+		}
+	default:
+		log.Fatalf("unknown STORAGE_BACKEND %q, use local or s3", cfg.StorageBackend)
+	}
+
+	// Book metadata catalog
+	var metadata bookmeta.Provider
+	switch cfg.MetadataProvider {
+	case "openlibrary":
+		metadata = bookmeta.NewOpenLibrary("", nil)
+	case "googlebooks":
+		metadata = bookmeta.NewGoogleBooks("", cfg.GoogleBooksAPIKey, nil)
+	case "fake":
+		metadata, err = bookmeta.NewFake(cfg.MetadataFixtures)
+		if err != nil {
+			log.Fatalf("failed to load metadata fixtures: %v", err)
+		}
+	case "none":
+	default:
+		log.Fatalf("unknown METADATA_PROVIDER %q, use openlibrary, googlebooks, fake or none", cfg.MetadataProvider)
+	}
+	if metadata != nil {
+		metadata = bookmeta.NewCached(
+			bookmeta.NewBreaker(bookmeta.WithTimeout(metadata, cfg.MetadataTimeout), cfg.MetadataCircuitThreshold, cfg.MetadataCircuitCooldown),
+			cfg.MetadataCacheTTL, time.Hour, 10000)
+	}
 
 	// Repositories and Use Cases
 	userRepo := repository.NewUserRepository(db)
-	userUseCase := usecase.NewUserUseCase(userRepo, jwtService)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	revokedTokenRepo := repository.NewCachedRevokedTokenRepository(repository.NewRevokedTokenRepository(db), cfg.RevocationCacheTTL)
+	actionTokenRepo := repository.NewActionTokenRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	privacyRepo := repository.NewPrivacyRepository(db)
+	followRepo := repository.NewFollowRepository(db)
+	blockRepo := repository.NewBlockRepository(db)
+	nearbyRepo := repository.NewNearbyRepository(db)
+	bookRepo := repository.NewBookRepository(db)
+	importRepo := repository.NewImportRepository(db)
+	accountUseCase := usecase.NewAccountUseCase(userRepo, refreshTokenRepo, actionTokenRepo, auditRepo, mail, passwordHasher, usecase.AccountSettings{
+		AppBaseURL:           cfg.AppBaseURL,
+		PasswordResetTTL:     cfg.PasswordResetTTL,
+		EmailVerificationTTL: cfg.EmailVerificationTTL,
+		AccountUnlockTTL:     cfg.AccountUnlockTTL,
+		ResendCooldown:       cfg.VerificationResendCooldown,
+		ResendDailyLimit:     cfg.VerificationResendDailyLimit,
+	})
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	loginGuard := usecase.NewLoginGuard(userRepo, auditRepo, accountUseCase, passwordHasher, usecase.LoginProtectionSettings{
+		FreeAttempts:            cfg.LoginFreeAttempts,
+		BackoffBase:             cfg.LoginBackoffBase,
+		BackoffMax:              cfg.LoginBackoffMax,
+		AccountLockoutThreshold: cfg.AccountLockoutThreshold,
+		IPLockoutThreshold:      cfg.IPLockoutThreshold,
+		LockoutDuration:         cfg.LockoutDuration,
+		IPFailureWindow:         cfg.LoginBackoffMax,
+	})
+	mfaUseCase := usecase.NewMFAUseCase(userRepo, recoveryCodeRepo, loginGuard, cfg.TOTPIssuer)
+	userUseCase := usecase.NewUserUseCase(userRepo, refreshTokenRepo, revokedTokenRepo, roleRepo, identityRepo, sessionRepo, auditRepo, accountUseCase, mfaUseCase, jwtService, passwordHasher, loginGuard, usecase.UserSettings{
+		RefreshTokenTTL:     cfg.RefreshTokenTTL,
+		MFATokenTTL:         cfg.MFATokenTTL,
+		DeletionGracePeriod: cfg.AccountDeletionGracePeriod,
+	})
+
+	adminUseCase := usecase.NewAdminUseCase(userRepo, roleRepo, refreshTokenRepo, auditRepo)
+	var oidcProviders []*oidc.Provider
+	for _, providerCfg := range cfg.OIDCProviders {
+		oidcProviders = append(oidcProviders, oidc.NewProvider(oidc.Config{
+			Name:         providerCfg.Name,
+			IssuerURL:    providerCfg.IssuerURL,
+			ClientID:     providerCfg.ClientID,
+			ClientSecret: providerCfg.ClientSecret,
+			RedirectURL:  providerCfg.RedirectURL,
+			Scopes:       providerCfg.Scopes,
+		}, nil))
+	}
+	oidcUseCase := usecase.NewOIDCUseCase(oidcProviders, identityRepo, userUseCase, cfg.OIDCStateTTL)
+	apiKeyUseCase := usecase.NewAPIKeyUseCase(apiKeyRepo, roleRepo, auditRepo)
+	privacyUseCase := usecase.NewPrivacyUseCase(userRepo, privacyRepo, auditRepo, files)
+	blockUseCase := usecase.NewBlockUseCase(userRepo, blockRepo)
+	followUseCase := usecase.NewFollowUseCase(userRepo, followRepo, blockUseCase)
+	profileUseCase := usecase.NewProfileUseCase(userRepo, files, usecase.ProfileSettings{
+		AvatarSizes:         cfg.AvatarSizes,
+		LocationPrecisionKm: float64(cfg.LocationPrecisionMeters) / 1000,
+	})
+	nearbyUseCase := usecase.NewNearbyUseCase(nearbyRepo)
+	bookUseCase := usecase.NewBookUseCase(bookRepo, userRepo, blockUseCase, metadata, usecase.BookSettings{
+		LocationPrecisionKm: float64(cfg.LocationPrecisionMeters) / 1000,
+	})
+	importUseCase := usecase.NewBookImportUseCase(importRepo, bookRepo, bookUseCase)
 
 	// Handlers
 	userHandler := handlers.NewUserHandler(userUseCase)
+	accountHandler := handlers.NewAccountHandler(accountUseCase)
+	mfaHandler := handlers.NewMFAHandler(mfaUseCase)
+	jwksHandler := handlers.NewJWKSHandler(jwtService)
+	adminHandler := handlers.NewAdminHandler(adminUseCase)
+	oidcHandler := handlers.NewOIDCHandler(oidcUseCase)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyUseCase)
+	privacyHandler := handlers.NewPrivacyHandler(privacyUseCase)
+	profileHandler := handlers.NewProfileHandler(profileUseCase, cfg.AvatarMaxBytes)
+	followHandler := handlers.NewFollowHandler(followUseCase)
+	blockHandler := handlers.NewBlockHandler(blockUseCase)
+	nearbyHandler := handlers.NewNearbyHandler(nearbyUseCase)
+	bookHandler := handlers.NewBookHandler(bookUseCase)
+	importHandler := handlers.NewImportHandler(importUseCase)
+
+	// Middleware
+	authenticator := middleware.NewAuthenticator(jwtService, userRepo, revokedTokenRepo, apiKeyRepo, roleRepo, sessionRepo)
+
+	// Periodically drop revocation entries for tokens that have expired anyway
+	// and abandoned OIDC login attempts, erase accounts whose deletion grace
+	// period has ended, and fail book imports cut off by a restart
+	go func() {
+		for range time.Tick(time.Hour) {
+			if err := revokedTokenRepo.DeleteExpired(); err != nil {
+				log.Printf("failed to purge revoked tokens: %v", err)
+			}
+			if err := identityRepo.DeleteExpiredStates(); err != nil {
+				log.Printf("failed to purge oidc login states: %v", err)
+			}
+			if purged, err := privacyUseCase.PurgeDeletedAccounts(); err != nil {
+				log.Printf("failed to purge deleted accounts: %v", err)
+			} else if purged > 0 {
+				log.Printf("erased %d deleted accounts", purged)
+			}
+			if failed, err := importUseCase.FailStaleImports(); err != nil {
+				log.Printf("failed to fail stale imports: %v", err)
+			} else if failed > 0 {
+				log.Printf("failed %d interrupted imports", failed)
+			}
+		}
+	}()
 
 	// Initialize Router
-	newRouter := router.NewRouter(userHandler, jwtKey)
+	newRouter := router.NewRouter(userHandler, accountHandler, mfaHandler, jwksHandler, adminHandler, oidcHandler, apiKeyHandler, privacyHandler, profileHandler, followHandler, blockHandler, nearbyHandler, bookHandler, importHandler, media, authenticator, cfg.RequireVerifiedEmail, cfg.TrustProxyHeaders)
 
 	// Start Server with dynamic port from config
 	port := cfg.ServerPort
@@ -61,6 +245,30 @@ func main() {
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%s", port), newRouter))
 }
 
+// loadJWTKeys builds the signing key set from the configured key files. If a
+// JWT_SECRET is still configured it is kept as a verify-only key, so tokens
+// issued before switching to asymmetric keys stay valid until they expire.
+func loadJWTKeys(cfg *config.Config) (*utils.KeySet, error) {
+	active, err := utils.LoadSigningKey(cfg.JWTActiveKey.ID, cfg.JWTActiveKey.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var retired []*utils.SigningKey
+	for _, keyCfg := range cfg.JWTRetiredKeys {
+		key, err := utils.LoadSigningKey(keyCfg.ID, keyCfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		retired = append(retired, key)
+	}
+	if cfg.JWTSecret != "" {
+		retired = append(retired, utils.NewHMACKey("", []byte(cfg.JWTSecret)))
+	}
+
+	return utils.NewKeySet(active, retired...)
+}
+
 //book-exchange-backend/
 //├── cmd/
 //│   └── server/
@@ -0,0 +1,58 @@
+// pkg/bookmeta/bookmeta.go
+package bookmeta
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("no metadata for this ISBN")
+	// ErrUnavailable means the catalog is failing and is not being asked
+	ErrUnavailable = errors.New("book catalog unavailable")
+)
+
+// Metadata is what a catalog knows about an edition
+type Metadata struct {
+	ISBN     string   `json:"isbn"`
+	Title    string   `json:"title"`
+	Authors  []string `json:"authors,omitempty"`
+	CoverURL string   `json:"cover_url,omitempty"`
+	// PublicationYear is 0 when unknown
+	PublicationYear int `json:"publication_year,omitempty"`
+	// Language is an ISO 639-1 code where the catalog allows, e.g. "en"
+	Language  string `json:"language,omitempty"`
+	PageCount int    `json:"page_count,omitempty"`
+}
+
+// Provider looks up books by ISBN-13 in an external catalog
+type Provider interface {
+	Lookup(ctx context.Context, isbn string) (*Metadata, error)
+}
+
+// ProviderFunc adapts a function to Provider
+type ProviderFunc func(ctx context.Context, isbn string) (*Metadata, error)
+
+func (f ProviderFunc) Lookup(ctx context.Context, isbn string) (*Metadata, error) {
+	return f(ctx, isbn)
+}
+
+// WithTimeout bounds every lookup of p
+func WithTimeout(p Provider, timeout time.Duration) Provider {
+	return ProviderFunc(func(ctx context.Context, isbn string) (*Metadata, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return p.Lookup(ctx, isbn)
+	})
+}
+
+var yearPattern = regexp.MustCompile(`\b(1[0-9]|20)[0-9]{2}\b`)
+
+// parseYear finds the year in free-form dates such as "October 1, 1988"
+func parseYear(date string) int {
+	year, _ := strconv.Atoi(yearPattern.FindString(date))
+	return year
+}
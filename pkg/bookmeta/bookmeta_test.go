@@ -0,0 +1,239 @@
+package bookmeta
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	knownISBN       = "9780140328721"
+	unknownISBN     = "9780000000019"
+	unavailableISBN = "9780000000002"
+)
+
+// counting wraps the built-in fake and counts the lookups that reach it
+type counting struct {
+	fake  *Fake
+	mu    sync.Mutex
+	calls int
+}
+
+func newCounting(t *testing.T) *counting {
+	t.Helper()
+	fake, err := NewFake("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &counting{fake: fake}
+}
+
+func (c *counting) Lookup(ctx context.Context, isbn string) (*Metadata, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	return c.fake.Lookup(ctx, isbn)
+}
+
+func (c *counting) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+// clock is a manually advanced time source
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestFakeFixtures(t *testing.T) {
+	fake, err := NewFake("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	meta, err := fake.Lookup(ctx, knownISBN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.ISBN != knownISBN || meta.Title != "Fantastic Mr. Fox" || meta.PublicationYear != 1988 {
+		t.Fatalf("unexpected metadata %+v", meta)
+	}
+	if _, err := fake.Lookup(ctx, unknownISBN); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unknown isbn: got %v, want ErrNotFound", err)
+	}
+	if _, err := fake.Lookup(ctx, unavailableISBN); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("failing fixture: got %v, want ErrUnavailable", err)
+	}
+}
+
+func TestCachedRemembersHitsAndMisses(t *testing.T) {
+	provider := newCounting(t)
+	now := &clock{t: time.Now()}
+	cache := NewCached(provider, time.Hour, time.Minute, 10)
+	cache.now = now.now
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Lookup(ctx, knownISBN); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cache.Lookup(ctx, unknownISBN); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("got %v, want ErrNotFound", err)
+		}
+	}
+	if got := provider.count(); got != 2 {
+		t.Fatalf("provider called %d times, want 2", got)
+	}
+
+	// The miss expires long before the hit
+	now.advance(2 * time.Minute)
+	cache.Lookup(ctx, knownISBN)
+	cache.Lookup(ctx, unknownISBN)
+	if got := provider.count(); got != 3 {
+		t.Fatalf("provider called %d times after the miss expired, want 3", got)
+	}
+
+	// Failures are not cached
+	cache.Lookup(ctx, unavailableISBN)
+	cache.Lookup(ctx, unavailableISBN)
+	if got := provider.count(); got != 5 {
+		t.Fatalf("provider called %d times after failures, want 5", got)
+	}
+}
+
+func TestCachedReturnsCopies(t *testing.T) {
+	cache := NewCached(newCounting(t), time.Hour, time.Minute, 10)
+	meta, err := cache.Lookup(context.Background(), knownISBN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta.Title = "changed"
+	again, _ := cache.Lookup(context.Background(), knownISBN)
+	if again.Title != "Fantastic Mr. Fox" {
+		t.Fatalf("cached entry was modified through a returned value: %q", again.Title)
+	}
+}
+
+func TestBreakerOpensAndHalfOpens(t *testing.T) {
+	provider := newCounting(t)
+	now := &clock{t: time.Now()}
+	breaker := NewBreaker(provider, 2, time.Minute)
+	breaker.now = now.now
+	ctx := context.Background()
+
+	// Unknown ISBNs are answers, not failures
+	for i := 0; i < 3; i++ {
+		breaker.Lookup(ctx, unknownISBN)
+	}
+	breaker.Lookup(ctx, unavailableISBN)
+	breaker.Lookup(ctx, unavailableISBN)
+	if got := provider.count(); got != 5 {
+		t.Fatalf("provider called %d times, want 5", got)
+	}
+
+	// Open: fail fast without asking the provider
+	if _, err := breaker.Lookup(ctx, knownISBN); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("open breaker: got %v, want ErrUnavailable", err)
+	}
+	if got := provider.count(); got != 5 {
+		t.Fatalf("open breaker reached the provider")
+	}
+
+	// Half-open: a failed trial opens it for another cooldown
+	now.advance(time.Minute + time.Second)
+	breaker.Lookup(ctx, unavailableISBN)
+	if got := provider.count(); got != 6 {
+		t.Fatalf("trial lookup did not reach the provider")
+	}
+	if _, err := breaker.Lookup(ctx, knownISBN); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("breaker closed after a failed trial: %v", err)
+	}
+
+	// A successful trial closes it
+	now.advance(time.Minute + time.Second)
+	if _, err := breaker.Lookup(ctx, knownISBN); err != nil {
+		t.Fatalf("trial lookup: %v", err)
+	}
+	if _, err := breaker.Lookup(ctx, knownISBN); err != nil {
+		t.Fatalf("breaker still open after a successful trial: %v", err)
+	}
+}
+
+func TestBreakerAllowsOneTrialAtATime(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	fail := true
+	provider := ProviderFunc(func(ctx context.Context, isbn string) (*Metadata, error) {
+		if fail {
+			return nil, ErrUnavailable
+		}
+		started <- struct{}{}
+		<-release
+		return &Metadata{ISBN: isbn}, nil
+	})
+	now := &clock{t: time.Now()}
+	breaker := NewBreaker(provider, 1, time.Minute)
+	breaker.now = now.now
+	ctx := context.Background()
+
+	breaker.Lookup(ctx, knownISBN)
+	fail = false
+	now.advance(2 * time.Minute)
+
+	done := make(chan error)
+	go func() {
+		_, err := breaker.Lookup(ctx, knownISBN)
+		done <- err
+	}()
+	<-started
+	if _, err := breaker.Lookup(ctx, knownISBN); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("second lookup during the trial: got %v, want ErrUnavailable", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("trial lookup: %v", err)
+	}
+}
+
+func TestBreakerIgnoresCanceledLookups(t *testing.T) {
+	provider := newCounting(t)
+	breaker := NewBreaker(provider, 1, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	breaker.Lookup(ctx, knownISBN)
+	if _, err := breaker.Lookup(context.Background(), knownISBN); err != nil {
+		t.Fatalf("canceled lookup opened the breaker: %v", err)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	slow := ProviderFunc(func(ctx context.Context, isbn string) (*Metadata, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return &Metadata{ISBN: isbn}, nil
+		}
+	})
+
+	start := time.Now()
+	_, err := WithTimeout(slow, 20*time.Millisecond).Lookup(context.Background(), knownISBN)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("lookup took %v despite the timeout", elapsed)
+	}
+
+	// Each lookup gets its own deadline
+	fake, _ := NewFake("")
+	if _, err := WithTimeout(fake, time.Second).Lookup(context.Background(), knownISBN); err != nil {
+		t.Fatalf("lookup after a timed out one: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// pkg/bookmeta/breaker.go
+package bookmeta
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Breaker stops calling a provider that keeps failing. After threshold
+// consecutive failures it fails fast with ErrUnavailable for cooldown; then
+// a single trial lookup is let through, and its outcome closes the breaker
+// or opens it for another cooldown.
+type Breaker struct {
+	provider  Provider
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+// NewBreaker wraps provider with a circuit breaker
+func NewBreaker(provider Provider, threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{provider: provider, threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+func (b *Breaker) Lookup(ctx context.Context, isbn string) (*Metadata, error) {
+	if !b.allow() {
+		return nil, ErrUnavailable
+	}
+	meta, err := b.provider.Lookup(ctx, isbn)
+	// A canceled request says nothing about the catalog's health
+	if ctx.Err() == context.Canceled {
+		b.release()
+		return meta, err
+	}
+	b.record(err == nil || errors.Is(err, ErrNotFound))
+	return meta, err
+}
+
+// allow reports whether a lookup may go through, claiming the trial slot
+// when the cooldown is over
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.trial || b.now().Before(b.openUntil) {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+func (b *Breaker) release() {
+	b.mu.Lock()
+	b.trial = false
+	b.mu.Unlock()
+}
+
+func (b *Breaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if ok {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,91 @@
+// pkg/bookmeta/cache.go
+package bookmeta
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Cached remembers lookups of a provider in memory. ISBNs the catalog does
+// not know are remembered for notFoundTTL, so repeated misses do not reach
+// the catalog either; other errors are not cached.
+type Cached struct {
+	provider    Provider
+	ttl         time.Duration
+	notFoundTTL time.Duration
+	maxEntries  int
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	meta      *Metadata
+	expiresAt time.Time
+}
+
+// NewCached wraps provider with a cache of at most maxEntries ISBNs
+func NewCached(provider Provider, ttl, notFoundTTL time.Duration, maxEntries int) *Cached {
+	return &Cached{
+		provider:    provider,
+		ttl:         ttl,
+		notFoundTTL: notFoundTTL,
+		maxEntries:  maxEntries,
+		now:         time.Now,
+		entries:     make(map[string]cacheEntry),
+	}
+}
+
+func (c *Cached) Lookup(ctx context.Context, isbn string) (*Metadata, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[isbn]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expiresAt) {
+		if entry.meta == nil {
+			return nil, ErrNotFound
+		}
+		copied := *entry.meta
+		return &copied, nil
+	}
+
+	meta, err := c.provider.Lookup(ctx, isbn)
+	switch {
+	case err == nil:
+		c.store(isbn, meta, c.ttl)
+		copied := *meta
+		return &copied, nil
+	case errors.Is(err, ErrNotFound):
+		c.store(isbn, nil, c.notFoundTTL)
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (c *Cached) store(isbn string, meta *Metadata, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= c.maxEntries {
+		for key, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		// Still full of live entries: make room by dropping arbitrary ones
+		for key := range c.entries {
+			if len(c.entries) < c.maxEntries {
+				break
+			}
+			delete(c.entries, key)
+		}
+	}
+	c.entries[isbn] = cacheEntry{meta: meta, expiresAt: now.Add(ttl)}
+}
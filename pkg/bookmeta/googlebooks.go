@@ -0,0 +1,76 @@
+// pkg/bookmeta/googlebooks.go
+package bookmeta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GoogleBooks looks books up through the Google Books volumes API
+type GoogleBooks struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewGoogleBooks creates a GoogleBooks provider; baseURL defaults to
+// https://www.googleapis.com and client to one with a timeout. The API key
+// is optional but raises the quota.
+func NewGoogleBooks(baseURL, apiKey string, client *http.Client) *GoogleBooks {
+	if baseURL == "" {
+		baseURL = "https://www.googleapis.com"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &GoogleBooks{baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey, client: client}
+}
+
+type googleVolumes struct {
+	Items []struct {
+		VolumeInfo struct {
+			Title         string   `json:"title"`
+			Subtitle      string   `json:"subtitle"`
+			Authors       []string `json:"authors"`
+			PublishedDate string   `json:"publishedDate"`
+			Language      string   `json:"language"`
+			PageCount     int      `json:"pageCount"`
+			ImageLinks    struct {
+				Thumbnail string `json:"thumbnail"`
+			} `json:"imageLinks"`
+		} `json:"volumeInfo"`
+	} `json:"items"`
+}
+
+func (p *GoogleBooks) Lookup(ctx context.Context, isbn string) (*Metadata, error) {
+	query := url.Values{"q": {"isbn:" + isbn}, "maxResults": {"1"}}
+	if p.apiKey != "" {
+		query.Set("key", p.apiKey)
+	}
+	var result googleVolumes
+	if err := getJSON(ctx, p.client, p.baseURL+"/books/v1/volumes?"+query.Encode(), &result); err != nil {
+		return nil, fmt.Errorf("google books: %w", err)
+	}
+	if len(result.Items) == 0 || result.Items[0].VolumeInfo.Title == "" {
+		return nil, ErrNotFound
+	}
+
+	info := result.Items[0].VolumeInfo
+	title := info.Title
+	if info.Subtitle != "" {
+		title += ": " + info.Subtitle
+	}
+	return &Metadata{
+		ISBN:            isbn,
+		Title:           title,
+		Authors:         info.Authors,
+		CoverURL:        strings.Replace(info.ImageLinks.Thumbnail, "http://", "https://", 1),
+		PublicationYear: parseYear(info.PublishedDate),
+		Language:        info.Language,
+		PageCount:       info.PageCount,
+	}, nil
+}
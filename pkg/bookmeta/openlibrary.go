@@ -0,0 +1,106 @@
+// pkg/bookmeta/openlibrary.go
+package bookmeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// marcLanguages maps the MARC codes Open Library uses to ISO 639-1
+var marcLanguages = map[string]string{
+	"ara": "ar", "chi": "zh", "cze": "cs", "dan": "da", "dut": "nl", "eng": "en",
+	"fin": "fi", "fre": "fr", "ger": "de", "gre": "el", "heb": "he", "hin": "hi",
+	"hun": "hu", "ita": "it", "jpn": "ja", "kaz": "kk", "kor": "ko", "nor": "no",
+	"per": "fa", "pol": "pl", "por": "pt", "rum": "ro", "rus": "ru", "spa": "es",
+	"swe": "sv", "tur": "tr", "ukr": "uk",
+}
+
+// OpenLibrary looks books up through the Open Library books API
+type OpenLibrary struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewOpenLibrary creates an OpenLibrary provider; baseURL defaults to
+// https://openlibrary.org and client to one with a timeout
+func NewOpenLibrary(baseURL string, client *http.Client) *OpenLibrary {
+	if baseURL == "" {
+		baseURL = "https://openlibrary.org"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OpenLibrary{baseURL: strings.TrimRight(baseURL, "/"), client: client}
+}
+
+type openLibraryBook struct {
+	Details struct {
+		Title         string                  `json:"title"`
+		Authors       []struct{ Name string } `json:"authors"`
+		PublishDate   string                  `json:"publish_date"`
+		NumberOfPages int                     `json:"number_of_pages"`
+		Covers        []int                   `json:"covers"`
+		Languages     []struct{ Key string }  `json:"languages"`
+	} `json:"details"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+func (p *OpenLibrary) Lookup(ctx context.Context, isbn string) (*Metadata, error) {
+	key := "ISBN:" + isbn
+	query := url.Values{"bibkeys": {key}, "format": {"json"}, "jscmd": {"details"}}
+	var result map[string]openLibraryBook
+	if err := getJSON(ctx, p.client, p.baseURL+"/api/books?"+query.Encode(), &result); err != nil {
+		return nil, fmt.Errorf("open library: %w", err)
+	}
+	book, ok := result[key]
+	if !ok || book.Details.Title == "" {
+		return nil, ErrNotFound
+	}
+
+	meta := &Metadata{
+		ISBN:            isbn,
+		Title:           book.Details.Title,
+		PublicationYear: parseYear(book.Details.PublishDate),
+		PageCount:       book.Details.NumberOfPages,
+	}
+	for _, author := range book.Details.Authors {
+		meta.Authors = append(meta.Authors, author.Name)
+	}
+	if len(book.Details.Covers) > 0 && book.Details.Covers[0] > 0 {
+		meta.CoverURL = fmt.Sprintf("https://covers.openlibrary.org/b/id/%d-L.jpg", book.Details.Covers[0])
+	}
+	if len(book.Details.Languages) > 0 {
+		code := strings.TrimPrefix(book.Details.Languages[0].Key, "/languages/")
+		if iso, ok := marcLanguages[code]; ok {
+			code = iso
+		}
+		meta.Language = code
+	}
+	return meta, nil
+}
+
+// getJSON fetches url and decodes the JSON response into v
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,123 @@
+// pkg/geo/geo.go
+package geo
+
+import (
+	"errors"
+	"math"
+)
+
+// EarthRadiusKm is the mean radius of the earth
+const EarthRadiusKm = 6371.0088
+
+// kmPerDegree is the length of one degree of latitude
+const kmPerDegree = EarthRadiusKm * math.Pi / 180
+
+var ErrInvalidPoint = errors.New("latitude must be between -90 and 90 and longitude between -180 and 180")
+
+// Point is a WGS 84 position in decimal degrees
+type Point struct {
+	Lat float64
+	Lng float64
+}
+
+// Validate checks that the point is on the globe
+func (p Point) Validate() error {
+	if math.IsNaN(p.Lat) || math.IsNaN(p.Lng) || p.Lat < -90 || p.Lat > 90 || p.Lng < -180 || p.Lng > 180 {
+		return ErrInvalidPoint
+	}
+	return nil
+}
+
+// Box is a latitude/longitude rectangle. When MinLng is greater than MaxLng
+// the box crosses the antimeridian.
+type Box struct {
+	MinLat, MinLng float64
+	MaxLat, MaxLng float64
+}
+
+// CrossesAntimeridian reports whether the box spans longitude ±180
+func (b Box) CrossesAntimeridian() bool {
+	return b.MinLng > b.MaxLng
+}
+
+// Center returns the middle of the box
+func (b Box) Center() Point {
+	span := b.MaxLng - b.MinLng
+	if b.CrossesAntimeridian() {
+		span += 360
+	}
+	return Point{Lat: (b.MinLat + b.MaxLat) / 2, Lng: normalizeLng(b.MinLng + span/2)}
+}
+
+// Diagonal returns the distance between the south-west and north-east corners
+func (b Box) Diagonal() float64 {
+	return Distance(Point{b.MinLat, b.MinLng}, Point{b.MaxLat, b.MaxLng})
+}
+
+// Distance returns the great-circle distance between a and b in kilometers
+func Distance(a, b Point) float64 {
+	dLat := radians(b.Lat - a.Lat)
+	dLng := radians(b.Lng - a.Lng)
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(radians(a.Lat))*math.Cos(radians(b.Lat))*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// BoundsAround returns the smallest box containing every point within
+// radiusKm of center. Matching a box is cheap with a plain B-tree index, so
+// it is used to narrow candidates before computing exact distances.
+func BoundsAround(center Point, radiusKm float64) Box {
+	dLat := radiusKm / kmPerDegree
+	box := Box{
+		MinLat: math.Max(-90, center.Lat-dLat),
+		MaxLat: math.Min(90, center.Lat+dLat),
+		MinLng: -180,
+		MaxLng: 180,
+	}
+	// Near a pole the circle covers every longitude
+	if box.MinLat == -90 || box.MaxLat == 90 {
+		return box
+	}
+	ratio := math.Sin(radiusKm/EarthRadiusKm) / math.Cos(radians(center.Lat))
+	if ratio >= 1 {
+		return box
+	}
+	dLng := degrees(math.Asin(ratio))
+	box.MinLng = normalizeLng(center.Lng - dLng)
+	box.MaxLng = normalizeLng(center.Lng + dLng)
+	return box
+}
+
+// Fuzz snaps p to the center of a grid cell roughly cellKm wide, so a stored
+// position reveals the neighbourhood but not the address. The same point
+// always lands in the same cell, which keeps repeated queries from averaging
+// the noise away.
+func Fuzz(p Point, cellKm float64) Point {
+	if cellKm <= 0 {
+		return p
+	}
+	latStep := cellKm / kmPerDegree
+	lat := math.Max(-90, math.Min(90, (math.Floor(p.Lat/latStep)+0.5)*latStep))
+	// Cells get narrower in degrees towards the poles; derive the longitude
+	// step from the snapped latitude so every point of a cell agrees on it
+	lngStep := math.Min(360, cellKm/(kmPerDegree*math.Max(math.Cos(radians(lat)), 0.01)))
+	lng := normalizeLng((math.Floor(p.Lng/lngStep) + 0.5) * lngStep)
+	return Point{Lat: lat, Lng: lng}
+}
+
+func normalizeLng(lng float64) float64 {
+	for lng > 180 {
+		lng -= 360
+	}
+	for lng < -180 {
+		lng += 360
+	}
+	return lng
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,181 @@
+package geo
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Point
+		want float64
+	}{
+		{"same point", Point{48.8566, 2.3522}, Point{48.8566, 2.3522}, 0},
+		{"one degree along the equator", Point{0, 0}, Point{0, 1}, EarthRadiusKm * math.Pi / 180},
+		{"quarter of the equator", Point{0, 0}, Point{0, 90}, EarthRadiusKm * math.Pi / 2},
+		{"pole to pole", Point{90, 0}, Point{-90, 0}, EarthRadiusKm * math.Pi},
+		{"across the antimeridian", Point{0, 179.5}, Point{0, -179.5}, EarthRadiusKm * math.Pi / 180},
+		{"Paris to London", Point{48.8566, 2.3522}, Point{51.5074, -0.1278}, 343.5},
+		{"New York to Los Angeles", Point{40.7128, -74.0060}, Point{34.0522, -118.2437}, 3936},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Distance(tt.a, tt.b)
+			// City distances are rounded; the others are exact
+			if math.Abs(got-tt.want) > 0.001*tt.want+1e-9 {
+				t.Errorf("got %.3f km, want %.3f km", got, tt.want)
+			}
+			if back := Distance(tt.b, tt.a); math.Abs(back-got) > 1e-9 {
+				t.Errorf("distance is not symmetric: %.6f and %.6f", got, back)
+			}
+		})
+	}
+}
+
+func TestDiagonal(t *testing.T) {
+	degree := EarthRadiusKm * math.Pi / 180
+	tests := []struct {
+		name string
+		box  Box
+		want float64
+	}{
+		{"empty", Box{MinLat: 10, MinLng: 20, MaxLat: 10, MaxLng: 20}, 0},
+		{"along a meridian", Box{MinLat: 0, MinLng: 5, MaxLat: 1, MaxLng: 5}, degree},
+		{"across the antimeridian", Box{MinLat: 0, MinLng: 179.5, MaxLat: 0, MaxLng: -179.5}, degree},
+	}
+	for _, tt := range tests {
+		if got := tt.box.Diagonal(); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: got %.6f km, want %.6f km", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBoundsAroundEquator(t *testing.T) {
+	degree := EarthRadiusKm * math.Pi / 180
+	box := BoundsAround(Point{0, 0}, degree)
+	want := Box{MinLat: -1, MinLng: -1, MaxLat: 1, MaxLng: 1}
+	for _, pair := range [][2]float64{
+		{box.MinLat, want.MinLat}, {box.MaxLat, want.MaxLat},
+		{box.MinLng, want.MinLng}, {box.MaxLng, want.MaxLng},
+	} {
+		if math.Abs(pair[0]-pair[1]) > 1e-9 {
+			t.Fatalf("got %+v, want %+v", box, want)
+		}
+	}
+}
+
+func TestBoundsAroundPoles(t *testing.T) {
+	for _, center := range []Point{{89.5, 10}, {-89.5, -170}, {90, 0}} {
+		box := BoundsAround(center, 100)
+		if box.MinLng != -180 || box.MaxLng != 180 {
+			t.Errorf("%+v: got longitudes %v to %v, want every longitude", center, box.MinLng, box.MaxLng)
+		}
+		if box.MaxLat > 90 || box.MinLat < -90 {
+			t.Errorf("%+v: latitudes %v to %v leave the globe", center, box.MinLat, box.MaxLat)
+		}
+	}
+}
+
+func TestBoundsAroundAntimeridian(t *testing.T) {
+	for _, center := range []Point{{0, 179.9}, {-45, -179.9}, {60, 180}} {
+		box := BoundsAround(center, 50)
+		if !box.CrossesAntimeridian() {
+			t.Fatalf("%+v: got %+v, want a box crossing the antimeridian", center, box)
+		}
+		if got := box.Center(); Distance(got, center) > 1e-6 {
+			t.Errorf("%+v: box centered on %+v", center, got)
+		}
+	}
+}
+
+// Every point of the circle must fall inside its bounding box
+func TestBoundsAroundContainsCircle(t *testing.T) {
+	centers := []Point{{0, 0}, {51.5, -0.12}, {-33.9, 151.2}, {75, 179.8}, {-70, -179.5}, {88, 45}}
+	for _, center := range centers {
+		for _, radius := range []float64{1, 25, 500} {
+			box := BoundsAround(center, radius)
+			for bearing := 0.0; bearing < 360; bearing += 5 {
+				p := destination(center, bearing, radius)
+				if !contains(box, p, 1e-9) {
+					t.Errorf("center %+v radius %v: %+v at bearing %v is outside %+v", center, radius, p, bearing, box)
+				}
+			}
+		}
+	}
+}
+
+func TestFuzzIsDeterministic(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		p := randomPoint(rng)
+		if a, b := Fuzz(p, 1), Fuzz(p, 1); a != b {
+			t.Fatalf("Fuzz(%+v) gave %+v and %+v", p, a, b)
+		}
+	}
+}
+
+func TestFuzzStaysWithinCell(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	points := []Point{{90, 0}, {-90, 0}, {89.999, 120}, {0, 180}, {0, -180}, {45, 179.999}, {-45, -179.999}}
+	for i := 0; i < 5000; i++ {
+		points = append(points, randomPoint(rng))
+	}
+	for _, cellKm := range []float64{0.5, 1, 5, 50} {
+		for _, p := range points {
+			got := Fuzz(p, cellKm)
+			if err := got.Validate(); err != nil {
+				t.Fatalf("Fuzz(%+v, %v) = %+v: %v", p, cellKm, got, err)
+			}
+			// The cell center is at most half a cell diagonal away
+			if d := Distance(p, got); d > cellKm*math.Sqrt2/2+1e-6 {
+				t.Errorf("Fuzz(%+v, %v) = %+v, %.3f km away", p, cellKm, got, d)
+			}
+		}
+	}
+}
+
+func TestFuzzSnapsToCellCenters(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 1000; i++ {
+		p := randomPoint(rng)
+		p.Lat = math.Max(-80, math.Min(80, p.Lat))
+		center := Fuzz(p, 1)
+		if again := Fuzz(center, 1); Distance(again, center) > 1e-9 {
+			t.Fatalf("cell center %+v of %+v moved to %+v", center, p, again)
+		}
+	}
+}
+
+func TestFuzzDisabled(t *testing.T) {
+	p := Point{48.8566, 2.3522}
+	if got := Fuzz(p, 0); got != p {
+		t.Errorf("got %+v, want %+v unchanged", got, p)
+	}
+}
+
+func randomPoint(rng *rand.Rand) Point {
+	return Point{Lat: rng.Float64()*180 - 90, Lng: rng.Float64()*360 - 180}
+}
+
+// destination returns the point distanceKm from p along the initial bearing,
+// in degrees clockwise from north
+func destination(p Point, bearing, distanceKm float64) Point {
+	lat1, lng1 := radians(p.Lat), radians(p.Lng)
+	angle := distanceKm / EarthRadiusKm
+	theta := radians(bearing)
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(angle) + math.Cos(lat1)*math.Sin(angle)*math.Cos(theta))
+	lng2 := lng1 + math.Atan2(math.Sin(theta)*math.Sin(angle)*math.Cos(lat1), math.Cos(angle)-math.Sin(lat1)*math.Sin(lat2))
+	return Point{Lat: degrees(lat2), Lng: normalizeLng(degrees(lng2))}
+}
+
+func contains(b Box, p Point, eps float64) bool {
+	if p.Lat < b.MinLat-eps || p.Lat > b.MaxLat+eps {
+		return false
+	}
+	if b.CrossesAntimeridian() {
+		return p.Lng >= b.MinLng-eps || p.Lng <= b.MaxLng+eps
+	}
+	return p.Lng >= b.MinLng-eps && p.Lng <= b.MaxLng+eps
+}
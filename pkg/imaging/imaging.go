@@ -0,0 +1,82 @@
+// pkg/imaging/imaging.go
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooManyPixels     = errors.New("image dimensions are too large")
+)
+
+// Image is a decoded upload. Metadata such as EXIF is not carried over, only
+// the orientation needed to display the pixels upright.
+type Image struct {
+	image.Image
+	Format      string
+	Orientation Orientation
+}
+
+// Decode reads a JPEG, PNG, GIF (first frame) or WebP image. The dimensions
+// are checked before decoding so that small files describing huge images are
+// rejected without allocating memory for them.
+func Decode(data []byte, maxPixels int) (*Image, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	orientation := OrientationNormal
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	return &Image{Image: img, Format: format, Orientation: orientation}, nil
+}
+
+// SquareThumbnail crops the largest centered square, scales it to size×size
+// and turns it upright. Transparent areas are filled with white.
+func SquareThumbnail(img *Image, size int) image.Image {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	crop := image.Rect(0, 0, side, side).Add(bounds.Min).
+		Add(image.Pt((bounds.Dx()-side)/2, (bounds.Dy()-side)/2))
+
+	thumb := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(thumb, thumb.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	xdraw.CatmullRom.Scale(thumb, thumb.Bounds(), img.Image, crop, xdraw.Over, nil)
+
+	// A centered square stays centered under every orientation, so rotating
+	// the small thumbnail is equivalent to rotating the whole image first
+	return img.Orientation.apply(thumb)
+}
+
+// EncodeJPEG encodes an image without any metadata
+func EncodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
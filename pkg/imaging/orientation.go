@@ -0,0 +1,138 @@
+// pkg/imaging/orientation.go
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// Orientation is the EXIF orientation tag: how the stored pixels must be
+// transformed to display the image upright
+type Orientation int
+
+const (
+	OrientationNormal     Orientation = 1
+	OrientationFlipH      Orientation = 2
+	OrientationRotate180  Orientation = 3
+	OrientationFlipV      Orientation = 4
+	OrientationTranspose  Orientation = 5
+	OrientationRotate90   Orientation = 6 // clockwise
+	OrientationTransverse Orientation = 7
+	OrientationRotate270  Orientation = 8 // clockwise
+)
+
+const exifOrientationTag = 0x0112
+
+// apply returns the image transformed to display upright
+func (o Orientation) apply(src *image.RGBA) image.Image {
+	if o <= OrientationNormal || o > OrientationRotate270 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	swap := o >= OrientationTranspose
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if swap {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case OrientationFlipH:
+				dx, dy = w-1-x, y
+			case OrientationRotate180:
+				dx, dy = w-1-x, h-1-y
+			case OrientationFlipV:
+				dx, dy = x, h-1-y
+			case OrientationTranspose:
+				dx, dy = y, x
+			case OrientationRotate90:
+				dx, dy = h-1-y, x
+			case OrientationTransverse:
+				dx, dy = h-1-y, w-1-x
+			case OrientationRotate270:
+				dx, dy = y, w-1-x
+			}
+			si := src.PixOffset(src.Rect.Min.X+x, src.Rect.Min.Y+y)
+			di := dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the orientation from the EXIF segment of a JPEG file
+func jpegOrientation(data []byte) Orientation {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return OrientationNormal
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			break
+		}
+		marker := data[pos+1]
+		if marker == 0xFF {
+			// Fill byte before a marker
+			pos++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			// Metadata segments come before the image data
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			break
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return OrientationNormal
+}
+
+// exifOrientation looks up the orientation tag in the first IFD of a TIFF
+// structure
+func exifOrientation(tiff []byte) Orientation {
+	if len(tiff) < 8 {
+		return OrientationNormal
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return OrientationNormal
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return OrientationNormal
+	}
+
+	ifd := uint64(order.Uint32(tiff[4:]))
+	if ifd+2 > uint64(len(tiff)) {
+		return OrientationNormal
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := int(ifd) + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+		// A SHORT value is stored in the first bytes of the value field
+		if o := Orientation(order.Uint16(tiff[entry+8:])); o >= OrientationNormal && o <= OrientationRotate270 {
+			return o
+		}
+		break
+	}
+	return OrientationNormal
+}
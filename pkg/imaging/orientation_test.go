@@ -0,0 +1,174 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+type ifdEntry struct {
+	tag   uint16
+	value uint16
+}
+
+// tiffHeader builds a TIFF structure with one IFD of SHORT entries at
+// ifdOffset; a zero offset places the IFD right after the header
+func tiffHeader(order binary.ByteOrder, ifdOffset uint32, entries ...ifdEntry) []byte {
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(&buf, order, uint16(42))
+	if ifdOffset == 0 {
+		ifdOffset = 8
+	}
+	binary.Write(&buf, order, ifdOffset)
+	binary.Write(&buf, order, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&buf, order, e.tag)
+		binary.Write(&buf, order, uint16(3)) // SHORT
+		binary.Write(&buf, order, uint32(1))
+		binary.Write(&buf, order, e.value)
+		binary.Write(&buf, order, uint16(0))
+	}
+	binary.Write(&buf, order, uint32(0)) // no next IFD
+	return buf.Bytes()
+}
+
+// jpegWith wraps segments, each a marker and its payload, in a JPEG file
+func jpegWith(segments ...[]byte) []byte {
+	data := []byte{0xFF, 0xD8}
+	for _, s := range segments {
+		data = append(data, s...)
+	}
+	// Start of scan and some image data
+	return append(data, 0xFF, 0xDA, 0x00, 0x02, 0x12, 0x34, 0xFF, 0xD9)
+}
+
+func segment(marker byte, payload []byte) []byte {
+	s := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(s[2:], uint16(len(payload)+2))
+	return append(s, payload...)
+}
+
+func exifSegment(tiff []byte) []byte {
+	return segment(0xE1, append([]byte("Exif\x00\x00"), tiff...))
+}
+
+func TestJPEGOrientationAllValues(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		for o := OrientationNormal; o <= OrientationRotate270; o++ {
+			tiff := tiffHeader(order, 0,
+				ifdEntry{0x010F, 7}, // Make, ignored
+				ifdEntry{exifOrientationTag, uint16(o)},
+			)
+			if got := jpegOrientation(jpegWith(exifSegment(tiff))); got != o {
+				t.Errorf("%v orientation %d: got %d", order, o, got)
+			}
+		}
+	}
+}
+
+func TestJPEGOrientationMalformed(t *testing.T) {
+	valid := tiffHeader(binary.BigEndian, 0, ifdEntry{exifOrientationTag, 6})
+	truncatedEntries := valid[:len(valid)-8]
+	tooManyEntries := append([]byte{}, valid...)
+	binary.BigEndian.PutUint16(tooManyEntries[8:], 40)
+	wrongMagic := append([]byte{}, valid...)
+	binary.BigEndian.PutUint16(wrongMagic[2:], 43)
+	badOrder := append([]byte{}, valid...)
+	copy(badOrder, "XX")
+	truncatedSegment := exifSegment(valid)
+	binary.BigEndian.PutUint16(truncatedSegment[2:], 0xFFF0)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not a JPEG", append([]byte("\x89PNG"), exifSegment(valid)...)},
+		{"empty", nil},
+		{"no EXIF segment", jpegWith(segment(0xE0, []byte("JFIF\x00\x01\x02")))},
+		{"unknown byte order", jpegWith(exifSegment(badOrder))},
+		{"wrong TIFF magic", jpegWith(exifSegment(wrongMagic))},
+		{"IFD offset past the end", jpegWith(exifSegment(tiffHeader(binary.LittleEndian, 0xFFFFFFF0, ifdEntry{exifOrientationTag, 6})))},
+		{"IFD offset at the last byte", jpegWith(exifSegment(tiffHeader(binary.LittleEndian, 25, ifdEntry{exifOrientationTag, 6})))},
+		{"truncated IFD entries", jpegWith(exifSegment(truncatedEntries))},
+		{"truncated TIFF header", jpegWith(exifSegment(valid[:6]))},
+		{"segment longer than the file", append([]byte{0xFF, 0xD8}, truncatedSegment...)},
+		{"segment length below two", jpegWith([]byte{0xFF, 0xE1, 0x00, 0x01}, exifSegment(valid))},
+		{"orientation zero", jpegWith(exifSegment(tiffHeader(binary.BigEndian, 0, ifdEntry{exifOrientationTag, 0})))},
+		{"orientation nine", jpegWith(exifSegment(tiffHeader(binary.BigEndian, 0, ifdEntry{exifOrientationTag, 9})))},
+		{"EXIF after the image data", append(jpegWith(), exifSegment(valid)...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != OrientationNormal {
+				t.Errorf("got %d, want %d", got, OrientationNormal)
+			}
+		})
+	}
+
+	// An entry count past the end only loses the entries that are missing
+	if got := jpegOrientation(jpegWith(exifSegment(tooManyEntries))); got != 6 {
+		t.Errorf("too many entries: got %d, want 6", got)
+	}
+}
+
+func TestJPEGOrientationSkipsOtherSegments(t *testing.T) {
+	tiff := tiffHeader(binary.LittleEndian, 0, ifdEntry{exifOrientationTag, 8})
+	data := jpegWith(
+		segment(0xE0, []byte("JFIF\x00\x01\x02")),
+		segment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x/>")),
+		[]byte{0xFF, 0xFF}, // fill bytes
+		exifSegment(tiff),
+	)
+	if got := jpegOrientation(data); got != OrientationRotate270 {
+		t.Errorf("got %d, want %d", got, OrientationRotate270)
+	}
+}
+
+func TestOrientationApply(t *testing.T) {
+	// Stored pixels, 3 wide and 2 high:
+	//   A B C
+	//   D E F
+	const stored = "ABCDEF"
+	tests := []struct {
+		o    Orientation
+		want []string
+	}{
+		{OrientationNormal, []string{"ABC", "DEF"}},
+		{OrientationFlipH, []string{"CBA", "FED"}},
+		{OrientationRotate180, []string{"FED", "CBA"}},
+		{OrientationFlipV, []string{"DEF", "ABC"}},
+		{OrientationTranspose, []string{"AD", "BE", "CF"}},
+		{OrientationRotate90, []string{"DA", "EB", "FC"}},
+		{OrientationTransverse, []string{"FC", "EB", "DA"}},
+		{OrientationRotate270, []string{"CF", "BE", "AD"}},
+		{Orientation(0), []string{"ABC", "DEF"}},
+		{Orientation(9), []string{"ABC", "DEF"}},
+	}
+	for _, tt := range tests {
+		src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+		for i, label := range stored {
+			src.Set(i%3, i/3, color.RGBA{R: uint8(label), A: 255})
+		}
+
+		got := tt.o.apply(src)
+		if b := got.Bounds(); b.Dx() != len(tt.want[0]) || b.Dy() != len(tt.want) {
+			t.Errorf("orientation %d: got %dx%d, want %dx%d", tt.o, b.Dx(), b.Dy(), len(tt.want[0]), len(tt.want))
+			continue
+		}
+		for y, row := range tt.want {
+			for x, label := range row {
+				r, _, _, _ := got.At(got.Bounds().Min.X+x, got.Bounds().Min.Y+y).RGBA()
+				if byte(r>>8) != byte(label) {
+					t.Errorf("orientation %d: pixel (%d,%d) is %c, want %c", tt.o, x, y, byte(r>>8), label)
+				}
+			}
+		}
+	}
+}
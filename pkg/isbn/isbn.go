@@ -0,0 +1,114 @@
+// pkg/isbn/isbn.go
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalid = errors.New("invalid ISBN")
+
+// Normalize parses an ISBN-10 or ISBN-13, with or without hyphens or
+// spaces, checks its check digit and returns it as a bare ISBN-13
+func Normalize(value string) (string, error) {
+	digits := strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(value)))
+	digits = strings.TrimPrefix(digits, "ISBN")
+	digits = strings.TrimLeft(digits, ":")
+
+	switch len(digits) {
+	case 10:
+		if !valid10(digits) {
+			return "", ErrInvalid
+		}
+		return from10(digits), nil
+	case 13:
+		if !valid13(digits) {
+			return "", ErrInvalid
+		}
+		return digits, nil
+	default:
+		return "", ErrInvalid
+	}
+}
+
+// To10 converts a valid ISBN-13 to ISBN-10. Only ISBNs with the 978 prefix
+// have an ISBN-10 form.
+func To10(isbn13 string) (string, bool) {
+	if len(isbn13) != 13 || !strings.HasPrefix(isbn13, "978") {
+		return "", false
+	}
+	body := isbn13[3:12]
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X", true
+	}
+	return body + string(rune('0'+check)), true
+}
+
+// Variants returns every stored form an ISBN may have: the ISBN-13 and,
+// when there is one, the ISBN-10
+func Variants(value string) ([]string, error) {
+	isbn13, err := Normalize(value)
+	if err != nil {
+		return nil, err
+	}
+	if isbn10, ok := To10(isbn13); ok {
+		return []string{isbn13, isbn10}, nil
+	}
+	return []string{isbn13}, nil
+}
+
+func valid10(s string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		var digit int
+		switch {
+		case s[i] >= '0' && s[i] <= '9':
+			digit = int(s[i] - '0')
+		case s[i] == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += digit * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+func valid13(s string) bool {
+	sum := 0
+	for i := 0; i < 13; i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+		digit := int(s[i] - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// from10 converts a valid ISBN-10 to ISBN-13
+func from10(isbn10 string) string {
+	body := "978" + isbn10[:9]
+	sum := 0
+	for i := 0; i < 12; i++ {
+		digit := int(body[i] - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return body + string(rune('0'+(10-sum%10)%10))
+}
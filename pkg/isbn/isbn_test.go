@@ -0,0 +1,104 @@
+package isbn
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+		err   error
+	}{
+		{"ISBN-10", "0306406152", "9780306406157", nil},
+		{"ISBN-10 with hyphens", "0-306-40615-2", "9780306406157", nil},
+		{"ISBN-10 with spaces", " 0 306 40615 2 ", "9780306406157", nil},
+		{"ISBN-10 with X check digit", "0-8044-2957-X", "9780804429573", nil},
+		{"ISBN-10 with lowercase x", "080442957x", "9780804429573", nil},
+		{"ISBN-13", "9780306406157", "9780306406157", nil},
+		{"ISBN-13 with hyphens", "978-0-306-40615-7", "9780306406157", nil},
+		{"ISBN prefix", "ISBN: 978-0-306-40615-7", "9780306406157", nil},
+		{"979 prefix", "979-10-90636-07-1", "9791090636071", nil},
+
+		{"wrong ISBN-10 check digit", "0-306-40615-3", "", ErrInvalid},
+		{"wrong X check digit", "0-306-40615-X", "", ErrInvalid},
+		{"X before the check digit", "0-8044-295X-7", "", ErrInvalid},
+		{"wrong ISBN-13 check digit", "978-0-306-40615-8", "", ErrInvalid},
+		{"X in an ISBN-13", "978-0-8044-2957-X", "", ErrInvalid},
+		{"letters", "0-3O6-40615-2", "", ErrInvalid},
+		{"too short", "030640615", "", ErrInvalid},
+		{"too long", "97803064061570", "", ErrInvalid},
+		{"other separators", "0.306.40615.2", "", ErrInvalid},
+		{"empty", "", "", ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.value)
+			if err != tt.err {
+				t.Fatalf("Normalize(%q) error = %v, want %v", tt.value, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTo10(t *testing.T) {
+	tests := []struct {
+		isbn13 string
+		want   string
+		ok     bool
+	}{
+		{"9780306406157", "0306406152", true},
+		{"9780804429573", "080442957X", true},
+		{"9780000000002", "0000000000", true},
+		// 979 ISBNs were never issued as ISBN-10s
+		{"9791090636071", "", false},
+		{"030640615", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := To10(tt.isbn13)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("To10(%q) = %q, %v, want %q, %v", tt.isbn13, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// Converting to ISBN-10 and back must give the ISBN-13 we started from
+func TestRoundTrip(t *testing.T) {
+	for _, isbn13 := range []string{"9780306406157", "9780804429573", "9781861972712", "9780000000002"} {
+		isbn10, ok := To10(isbn13)
+		if !ok {
+			t.Fatalf("To10(%q) failed", isbn13)
+		}
+		if got, err := Normalize(isbn10); err != nil || got != isbn13 {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", isbn10, got, err, isbn13)
+		}
+	}
+}
+
+func TestVariants(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+		err   error
+	}{
+		{"0-306-40615-2", []string{"9780306406157", "0306406152"}, nil},
+		{"978-0-306-40615-7", []string{"9780306406157", "0306406152"}, nil},
+		{"0-8044-2957-x", []string{"9780804429573", "080442957X"}, nil},
+		{"979-10-90636-07-1", []string{"9791090636071"}, nil},
+		{"978-0-306-40615-8", nil, ErrInvalid},
+	}
+	for _, tt := range tests {
+		got, err := Variants(tt.value)
+		if err != tt.err {
+			t.Fatalf("Variants(%q) error = %v, want %v", tt.value, err, tt.err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Variants(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
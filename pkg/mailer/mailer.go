@@ -0,0 +1,74 @@
+// pkg/mailer/mailer.go
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outgoing email
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewLocalMailer returns a Mailer that never talks to an SMTP server. With a
+// directory it writes every message there as an .eml file; without one it
+// writes messages to the log.
+func NewLocalMailer(dir, from string) (Mailer, error) {
+	if dir == "" {
+		return &LogMailer{from: from}, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+// FileMailer writes messages to a directory, one file per message
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// Send writes the message as an RFC 5322 formatted file
+func (m *FileMailer) Send(msg Message) error {
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), uuid.NewString())
+	return os.WriteFile(filepath.Join(m.dir, name), []byte(format(m.from, msg, now)), 0o600)
+}
+
+// LogMailer writes messages to the standard logger
+type LogMailer struct {
+	from string
+}
+
+// Send logs the message
+func (m *LogMailer) Send(msg Message) error {
+	log.Printf("mail:\n%s", format(m.from, msg, time.Now()))
+	return nil
+}
+
+func format(from string, msg Message, date time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+	return b.String()
+}
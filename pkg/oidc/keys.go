@@ -0,0 +1,160 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// keyRefreshInterval limits how often an unknown kid triggers a JWKS refetch
+const keyRefreshInterval = time.Minute
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type verificationKey struct {
+	alg string
+	key interface{}
+}
+
+// keyCache holds the provider's signing keys. Keys are refetched when a
+// token names a kid we have not seen, which is how providers roll keys.
+type keyCache struct {
+	client *http.Client
+	uri    string
+
+	mu        sync.Mutex
+	keys      map[string]verificationKey
+	fetchedAt time.Time
+}
+
+func newKeyCache(client *http.Client, uri string) *keyCache {
+	return &keyCache{client: client, uri: uri}
+}
+
+func (c *keyCache) lookup(ctx context.Context, kid, alg string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.keys[kid]
+	if !ok && time.Since(c.fetchedAt) > keyRefreshInterval {
+		if err := c.refresh(ctx); err != nil {
+			return nil, err
+		}
+		key, ok = c.keys[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	// The algorithm is fixed by the key, never by the token header
+	if key.alg != alg {
+		return nil, fmt.Errorf("signing key %q does not use %s", kid, alg)
+	}
+	return key.key, nil
+}
+
+func (c *keyCache) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.uri, nil)
+	if err != nil {
+		return err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := doJSON(c.client, req, &set); err != nil {
+		return fmt.Errorf("oidc: fetch jwks: %w", err)
+	}
+
+	keys := make(map[string]verificationKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.verificationKey()
+		if err != nil {
+			// Skip keys we do not understand rather than failing all logins
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	return nil
+}
+
+func (k jwk) verificationKey() (verificationKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		alg := k.Alg
+		if alg == "" {
+			alg = "RS256"
+		}
+		return verificationKey{alg: alg, key: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var alg string
+		switch k.Crv {
+		case "P-256":
+			curve, alg = elliptic.P256(), "ES256"
+		case "P-384":
+			curve, alg = elliptic.P384(), "ES384"
+		case "P-521":
+			curve, alg = elliptic.P521(), "ES512"
+		default:
+			return verificationKey{}, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		return verificationKey{alg: alg, key: &ecdsa.PublicKey{Curve: curve, X: x, Y: y}}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return verificationKey{}, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return verificationKey{}, fmt.Errorf("invalid ed25519 key")
+		}
+		return verificationKey{alg: "EdDSA", key: ed25519.PublicKey(x)}, nil
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,218 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// mockProvider is a minimal identity provider serving discovery, a token
+// endpoint that hands out a prepared id token, and a JWKS that can be rotated
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu         sync.Mutex
+	kid        string
+	key        *rsa.PrivateKey
+	idToken    string
+	code       string
+	verifier   string
+	jwksServed int
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	m := &mockProvider{t: t}
+	m.rotate("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("code") != m.code ||
+			r.PostFormValue("code_verifier") != m.verifier || r.PostFormValue("client_id") != "client" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     m.idToken,
+			"expires_in":   3600,
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.jwksServed++
+		pub := m.key.PublicKey
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": m.kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}}})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// rotate replaces the signing key; the old one disappears from the JWKS
+func (m *mockProvider) rotate(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	m.mu.Lock()
+	m.kid, m.key = kid, key
+	m.mu.Unlock()
+}
+
+// sign issues an id token with the current key, after edit adjusts the claims
+func (m *mockProvider) sign(edit func(*idTokenClaims)) string {
+	now := time.Now()
+	claims := &idTokenClaims{
+		Nonce:         "nonce",
+		Email:         "reader@example.com",
+		EmailVerified: json.RawMessage(`"true"`),
+		Name:          "Reader",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.server.URL,
+			Subject:   "subject-1",
+			Audience:  jwt.ClaimStrings{"client"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+	if edit != nil {
+		edit(claims)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.kid
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return signed
+}
+
+func (m *mockProvider) provider() *Provider {
+	return NewProvider(Config{Name: "mock", IssuerURL: m.server.URL, ClientID: "client", RedirectURL: "http://app/callback"}, m.server.Client())
+}
+
+func TestExchangeAndVerify(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.provider()
+	ctx := context.Background()
+
+	verifier, err := NewCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.code, m.verifier, m.idToken = "code-1", verifier, m.sign(nil)
+
+	if _, err := p.Exchange(ctx, "code-1", "wrong-verifier"); err == nil {
+		t.Fatal("exchange with the wrong verifier succeeded")
+	}
+	tokens, err := p.Exchange(ctx, "code-1", verifier)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+
+	id, err := p.VerifyIDToken(ctx, tokens.IDToken, "nonce")
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if id.Subject != "subject-1" || id.Email != "reader@example.com" || !id.EmailVerified || id.Name != "Reader" {
+		t.Fatalf("unexpected claims %+v", id)
+	}
+}
+
+func TestVerifyIDTokenChecks(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.provider()
+
+	tests := []struct {
+		name    string
+		edit    func(*idTokenClaims)
+		nonce   string
+		wantErr error
+	}{
+		{"valid", nil, "nonce", nil},
+		{"nonce mismatch", nil, "other", ErrNonceMismatch},
+		{"wrong audience", func(c *idTokenClaims) { c.Audience = jwt.ClaimStrings{"someone-else"} }, "nonce", ErrInvalidIDToken},
+		{"several audiences without azp", func(c *idTokenClaims) { c.Audience = jwt.ClaimStrings{"client", "other"} }, "nonce", ErrInvalidIDToken},
+		{"several audiences with other azp", func(c *idTokenClaims) {
+			c.Audience, c.AuthorizedParty = jwt.ClaimStrings{"client", "other"}, "other"
+		}, "nonce", ErrInvalidIDToken},
+		{"several audiences with our azp", func(c *idTokenClaims) {
+			c.Audience, c.AuthorizedParty = jwt.ClaimStrings{"client", "other"}, "client"
+		}, "nonce", nil},
+		{"wrong issuer", func(c *idTokenClaims) { c.Issuer = "https://evil.example" }, "nonce", ErrInvalidIDToken},
+		{"expired", func(c *idTokenClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }, "nonce", ErrInvalidIDToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.VerifyIDToken(context.Background(), m.sign(tt.edit), tt.nonce)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRefetchesRotatedKeys(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.provider()
+	ctx := context.Background()
+
+	if _, err := p.VerifyIDToken(ctx, m.sign(nil), "nonce"); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	m.rotate("key-2")
+	rotated := m.sign(nil)
+
+	// Unknown kids refetch the JWKS at most once per keyRefreshInterval
+	if _, err := p.VerifyIDToken(ctx, rotated, "nonce"); err == nil {
+		t.Fatal("token signed with an unfetched key verified")
+	}
+	if m.jwksServed != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", m.jwksServed)
+	}
+
+	p.keys.fetchedAt = time.Now().Add(-2 * keyRefreshInterval)
+	if _, err := p.VerifyIDToken(ctx, rotated, "nonce"); err != nil {
+		t.Fatalf("verify after rotation: %v", err)
+	}
+	if m.jwksServed != 2 {
+		t.Fatalf("jwks fetched %d times, want 2", m.jwksServed)
+	}
+}
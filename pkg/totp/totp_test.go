@@ -0,0 +1,134 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 4226 and RFC 6238 test vectors
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+// RFC 4226 Appendix D
+func TestHOTPVectors(t *testing.T) {
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	key := []byte("12345678901234567890")
+	for counter, expected := range want {
+		if got := code(key, int64(counter)); got != expected {
+			t.Errorf("counter %d: got %s, want %s", counter, got, expected)
+		}
+	}
+}
+
+// RFC 6238 Appendix B, SHA-1 rows. The RFC lists 8-digit codes; 6-digit
+// codes are their last six digits.
+func TestTOTPVectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		want := tt.want[len(tt.want)-Digits:]
+		got, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("%d: %v", tt.unix, err)
+		}
+		if got != want {
+			t.Errorf("%d: got %s, want %s", tt.unix, got, want)
+		}
+		if _, ok := Validate(rfcSecret, want, time.Unix(tt.unix, 0)); !ok {
+			t.Errorf("%d: Validate rejected %s", tt.unix, want)
+		}
+	}
+}
+
+func TestValidateSkewWindow(t *testing.T) {
+	// The first and last second of step 1000
+	start := time.Unix(1000*int64(Period.Seconds()), 0)
+	end := start.Add(Period - time.Second)
+	codeAt := func(step int64) string {
+		c, err := Code(rfcSecret, time.Unix(step*int64(Period.Seconds()), 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		step     int64
+		at       time.Time
+		accepted bool
+	}{
+		{"current step", 1000, start, true},
+		{"previous step at the start", 999, start, true},
+		{"previous step at the end", 999, end, true},
+		{"next step at the start", 1001, start, true},
+		{"next step at the end", 1001, end, true},
+		{"two steps back", 998, start, false},
+		{"two steps back at the end", 998, end, false},
+		{"two steps ahead", 1002, end, false},
+		{"two steps ahead at the start", 1002, start, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, codeAt(tt.step), tt.at)
+			if ok != tt.accepted {
+				t.Fatalf("got accepted=%v, want %v", ok, tt.accepted)
+			}
+			if ok && step != tt.step {
+				t.Errorf("got step %d, want %d", step, tt.step)
+			}
+		})
+	}
+}
+
+func TestValidateInput(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	valid, err := Code(rfcSecret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		passcode string
+		accepted bool
+	}{
+		{"surrounding spaces", rfcSecret, " " + valid + "\n", true},
+		{"lowercase secret", strings.ToLower(rfcSecret), valid, true},
+		{"too short", rfcSecret, valid[1:], false},
+		{"too long", rfcSecret, valid + "0", false},
+		{"empty", rfcSecret, "", false},
+		{"invalid secret", "not base32!", valid, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(tt.secret, tt.passcode, now); ok != tt.accepted {
+				t.Errorf("got accepted=%v, want %v", ok, tt.accepted)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 20 {
+		t.Errorf("got a %d-byte key, want 20", len(key))
+	}
+}
@@ -2,64 +2,167 @@
 package utils
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
+// DefaultAccessTokenTTL is used when no explicit lifetime is configured
+const DefaultAccessTokenTTL = 15 * time.Minute
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the claims carried by access tokens issued by JWTService.
+// TokenVersion must match the user's current version for the token to be
+// accepted, which lets every outstanding token be revoked at once.
+type Claims struct {
+	UserID       int      `json:"user_id"`
+	TokenVersion int      `json:"ver"`
+	Roles        []string `json:"roles,omitempty"`
+	Permissions  []string `json:"perms,omitempty"`
+	// SessionID identifies the login session the token belongs to
+	SessionID string `json:"sid,omitempty"`
+	// Purpose is empty for access tokens and set for restricted tokens that
+	// must never be accepted as access tokens
+	Purpose string `json:"purpose,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// PurposeMFAPending marks a token proving the password step of a two-factor login
+const PurposeMFAPending = "mfa_pending"
+
 type JWTService interface {
-	GenerateToken(userID string) (string, error)
-	ValidateToken(token string) (*jwt.Token, error)
+	GenerateToken(claims Claims) (string, error)
+	ValidateToken(token string) (*Claims, error)
+	GenerateMFAToken(userID int, ttl time.Duration) (string, error)
+	ValidateMFAToken(token string) (*Claims, error)
+	TokenTTL() time.Duration
+	JWKS() JWKS
 }
 
+const defaultIssuer = "book-exchange"
+
 type jwtService struct {
-	secretKey string
-	issuer    string
+	keys   *KeySet
+	issuer string
+	ttl    time.Duration
+}
+
+// InitJWT creates an HS256 service signing with a shared secret
+func InitJWT(secret string, ttl time.Duration) JWTService {
+	return NewJWTService(secret, defaultIssuer, ttl)
+}
+
+func NewJWTService(secretKey, issuer string, ttl time.Duration) JWTService {
+	// Tokens signed with the shared secret have never carried a kid
+	keys, _ := NewKeySet(NewHMACKey("", []byte(secretKey)))
+	return NewJWTServiceWithKeys(keys, issuer, ttl)
 }
 
-func InitJWT(secret string) JWTService {
+// InitJWTWithKeys creates a service signing with the active key of keys
+func InitJWTWithKeys(keys *KeySet, ttl time.Duration) JWTService {
+	return NewJWTServiceWithKeys(keys, defaultIssuer, ttl)
+}
+
+func NewJWTServiceWithKeys(keys *KeySet, issuer string, ttl time.Duration) JWTService {
+	if ttl <= 0 {
+		ttl = DefaultAccessTokenTTL
+	}
 	return &jwtService{
-		secretKey: secret,
-		issuer:    "book-exchange",
+		keys:   keys,
+		issuer: issuer,
+		ttl:    ttl,
 	}
 }
 
-func NewJWTService(secretKey, issuer string) JWTService {
-	return &jwtService{
-		secretKey: secretKey,
-		issuer:    issuer,
+// GenerateToken signs an access token for the given claims, filling in the
+// registered claims (jti, sub, exp, iat, iss)
+func (j *jwtService) GenerateToken(claims Claims) (string, error) {
+	claims.Purpose = ""
+	return j.sign(claims, j.ttl)
+}
+
+// ValidateToken verifies the signature, expiry and issuer of an access token
+// and returns its claims
+func (j *jwtService) ValidateToken(tokenStr string) (*Claims, error) {
+	claims, err := j.parse(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// GenerateMFAToken signs a short-lived token that can only be exchanged for
+// an access token together with a second factor
+func (j *jwtService) GenerateMFAToken(userID int, ttl time.Duration) (string, error) {
+	return j.sign(Claims{UserID: userID, Purpose: PurposeMFAPending}, ttl)
+}
+
+// ValidateMFAToken verifies a token created by GenerateMFAToken
+func (j *jwtService) ValidateMFAToken(tokenStr string) (*Claims, error) {
+	claims, err := j.parse(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != PurposeMFAPending {
+		return nil, ErrInvalidToken
 	}
+	return claims, nil
 }
 
-func (j *jwtService) GenerateToken(userID string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-		"iat":     time.Now().Unix(),
-		"iss":     j.issuer,
+func (j *jwtService) sign(claims Claims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		Subject:   strconv.Itoa(claims.UserID),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		Issuer:    j.issuer,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secretKey))
+	active := j.keys.Active()
+	token := jwt.NewWithClaims(active.Method, claims)
+	if active.ID != "" {
+		token.Header["kid"] = active.ID
+	}
+	return token.SignedString(active.sign)
 }
 
-func (j *jwtService) ValidateToken(tokenStr string) (*jwt.Token, error) {
-	return jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		// Ensure the signing method is HMAC
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+func (j *jwtService) parse(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := j.keys.Lookup(kid)
+		if !ok {
 			return nil, jwt.ErrSignatureInvalid
 		}
-		return []byte(j.secretKey), nil
+		// Ensure the token uses the algorithm its key was configured for
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return key.verify, nil
 	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	if !claims.VerifyIssuer(j.issuer, true) || claims.UserID == 0 || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
 }
 
-// Utility functions for hashing
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+// TokenTTL returns the lifetime of issued access tokens
+func (j *jwtService) TokenTTL() time.Duration {
+	return j.ttl
 }
 
-func CheckPasswordHash(password, hash string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+// JWKS returns the public keys tokens can be verified with
+func (j *jwtService) JWKS() JWKS {
+	return j.keys.JWKS()
 }
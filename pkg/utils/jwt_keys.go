@@ -0,0 +1,172 @@
+// pkg/utils/jwt_keys.go
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrUnsupportedKey = errors.New("unsupported signing key type")
+	ErrNoActiveKey    = errors.New("no active signing key with a private part")
+)
+
+// SigningKey is a single key of a KeySet. Retired keys loaded from public key
+// files only verify tokens; keys with a private part can also sign.
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	// sign is the key handed to Method.Sign; nil for verify-only keys
+	sign interface{}
+	// verify is the key handed to Method.Verify
+	verify interface{}
+}
+
+// NewHMACKey wraps a shared secret as an HS256 key
+func NewHMACKey(kid string, secret []byte) *SigningKey {
+	return &SigningKey{ID: kid, Method: jwt.SigningMethodHS256, sign: secret, verify: secret}
+}
+
+// LoadSigningKey reads an RSA or Ed25519 key from a PEM file. Private keys
+// (PKCS#1 or PKCS#8) can sign; public keys (PKIX or PKCS#1) only verify.
+func LoadSigningKey(kid, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unexpected PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, sign: key, verify: &key.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, verify: key}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, sign: key, verify: key.Public()}, nil
+	case ed25519.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, verify: key}, nil
+	default:
+		return nil, fmt.Errorf("%s: %w", path, ErrUnsupportedKey)
+	}
+}
+
+// CanSign reports whether the key holds a private part
+func (k *SigningKey) CanSign() bool {
+	return k.sign != nil
+}
+
+// KeySet holds the key used to sign new tokens plus every key, active or
+// retired, that tokens may still be verified with, indexed by kid
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// NewKeySet builds a KeySet. Key IDs must be unique across active and retired keys.
+func NewKeySet(active *SigningKey, retired ...*SigningKey) (*KeySet, error) {
+	if active == nil || !active.CanSign() {
+		return nil, ErrNoActiveKey
+	}
+	ks := &KeySet{active: active, keys: map[string]*SigningKey{active.ID: active}}
+	for _, key := range retired {
+		if _, exists := ks.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+	return ks, nil
+}
+
+// Active returns the signing key
+func (ks *KeySet) Active() *SigningKey {
+	return ks.active
+}
+
+// Lookup finds a verification key by kid
+func (ks *KeySet) Lookup(kid string) (*SigningKey, bool) {
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// JWK is a single public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public halves of all asymmetric keys. Shared HMAC secrets
+// are never published.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		if jwk, ok := publicJWK(key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+func publicJWK(key *SigningKey) (JWK, bool) {
+	encode := base64.RawURLEncoding.EncodeToString
+	switch pub := key.verify.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+			KeyID:     key.ID,
+			N:         encode(pub.N.Bytes()),
+			E:         encode(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+			KeyID:     key.ID,
+			Curve:     "Ed25519",
+			X:         encode(pub),
+		}, true
+	}
+	return JWK{}, false
+}
@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported password hashing algorithms
+const (
+	PasswordAlgorithmBcrypt   = "bcrypt"
+	PasswordAlgorithmArgon2id = "argon2id"
+)
+
+var (
+	ErrPasswordMismatch     = errors.New("password does not match")
+	ErrUnknownPasswordHash  = errors.New("unknown password hash format")
+	ErrUnsupportedAlgorithm = errors.New("unsupported password hashing algorithm")
+)
+
+// PasswordHashConfig selects the algorithm and cost for new password hashes
+type PasswordHashConfig struct {
+	Algorithm  string
+	BcryptCost int
+	// Argon2id parameters; memory is in KiB
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+// PasswordHasher hashes passwords with the configured algorithm while still
+// verifying hashes produced by earlier configurations. NeedsRehash reports
+// hashes that should be replaced the next time the plaintext is known.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, hash string) error
+	NeedsRehash(hash string) bool
+}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+type passwordHasher struct {
+	cfg PasswordHashConfig
+}
+
+// NewPasswordHasher validates cfg and returns a hasher. Zero values select
+// bcrypt at its default cost and the RFC 9106 recommended argon2id
+// parameters for memory constrained environments.
+func NewPasswordHasher(cfg PasswordHashConfig) (PasswordHasher, error) {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = PasswordAlgorithmBcrypt
+	}
+	if cfg.BcryptCost == 0 {
+		cfg.BcryptCost = bcrypt.DefaultCost
+	}
+	if cfg.Argon2Memory == 0 {
+		cfg.Argon2Memory = 64 * 1024
+	}
+	if cfg.Argon2Iterations == 0 {
+		cfg.Argon2Iterations = 3
+	}
+	if cfg.Argon2Parallelism == 0 {
+		cfg.Argon2Parallelism = 4
+	}
+
+	switch cfg.Algorithm {
+	case PasswordAlgorithmBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost %d out of range", cfg.BcryptCost)
+		}
+	case PasswordAlgorithmArgon2id:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, cfg.Algorithm)
+	}
+	return &passwordHasher{cfg: cfg}, nil
+}
+
+func (h *passwordHasher) Hash(password string) (string, error) {
+	if h.cfg.Algorithm == PasswordAlgorithmArgon2id {
+		return h.hashArgon2id(password)
+	}
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.cfg.BcryptCost)
+	return string(bytes), err
+}
+
+func (h *passwordHasher) Verify(password, hash string) error {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return err
+		}
+		derived := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(derived, key) != 1 {
+			return ErrPasswordMismatch
+		}
+		return nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrPasswordMismatch
+	}
+	if err != nil {
+		return ErrUnknownPasswordHash
+	}
+	return nil
+}
+
+func (h *passwordHasher) NeedsRehash(hash string) bool {
+	if h.cfg.Algorithm == PasswordAlgorithmArgon2id {
+		params, _, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return true
+		}
+		return params.memory != h.cfg.Argon2Memory ||
+			params.iterations != h.cfg.Argon2Iterations ||
+			params.parallelism != h.cfg.Argon2Parallelism ||
+			len(key) != argon2KeyLength
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.cfg.BcryptCost
+}
+
+// hashArgon2id encodes the hash in the PHC string format used by the
+// reference implementation: $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+func (h *passwordHasher) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.cfg.Argon2Iterations, h.cfg.Argon2Memory, h.cfg.Argon2Parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.cfg.Argon2Memory, h.cfg.Argon2Iterations, h.cfg.Argon2Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+func decodeArgon2id(hash string) (*argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+	var params argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrUnknownPasswordHash
+	}
+	return &params, salt, key, nil
+}